@@ -1,117 +1,533 @@
+//go:build !nogui
+
 package internal
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/cli"
+	"github.com/greenrock64/chip8-interpreter/internal/lifecycle"
+	"github.com/greenrock64/chip8-interpreter/internal/roms"
+	"github.com/greenrock64/chip8-interpreter/internal/sound"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 var (
-	selectedInterpreterMode InterpreterMode = MODE_CHIP8
+	selectedInterpreterMode chip8.InterpreterMode = chip8.MODE_CHIP8
+	selectedQuirks                                = chip8.DefaultQuirks(selectedInterpreterMode)
+	// modeQuirks keeps the quirks set for each mode while another mode is selected
+	modeQuirks = map[chip8.InterpreterMode]chip8.Quirks{}
+	// selectedSeed is the random seed each ROM starts with, or nil to pick a new one every time
+	selectedSeed *uint64
+
+	// startupRom is started as soon as the app opens, when one is given on the command line
+	startupRom     RomFileReader
+	startupRomName string
 )
 
+// RomSettings are what a ROM is started with, taken from the Options menu when it's opened
+type RomSettings struct {
+	Mode   chip8.InterpreterMode
+	Quirks chip8.Quirks
+	// Seed is the random seed to start with, or nil to pick a new one
+	Seed *uint64
+}
+
+// selectedRomSettings takes a copy of the settings chosen in the Options menu. Those are only changed on the
+// UI thread, so this must be called there too, before starting the ROM in the background
+func selectedRomSettings() RomSettings {
+	settings := RomSettings{Mode: selectedInterpreterMode, Quirks: selectedQuirks}
+	if selectedSeed != nil {
+		seed := *selectedSeed
+		settings.Seed = &seed
+	}
+	return settings
+}
+
 type RomFileReader interface {
 	Read([]byte) (int, error)
 	Close() error
 }
 
-func StartRom(romName string, romFile RomFileReader) {
-	// Reset the display
-	clearDisplay()
+// StartRom resets the interpreter and starts a ROM, either from the built in library by name, or from romFile.
+// If the ROM can't be loaded, the interpreter is closed and the error is returned
+func StartRom(settings RomSettings, romName string, romFile RomFileReader) error {
+	// Reset the Interpreter and load the ROM
+	resetInterpreter(settings.Mode)
+	err := interpreterLifecycle.Load()
+	if err != nil {
+		return err
+	}
+	machine.SetQuirks(settings.Quirks)
+	if settings.Seed != nil {
+		machine.SetSeed(*settings.Seed)
+	} else {
+		machine.SetSeed(chip8.NewSeed())
+	}
+	clearRewindHistory()
 	tryOpenDisplay()
 
-	// Reset the Interpreter and load the ROM
-	resetInterpreter(selectedInterpreterMode)
 	if romName != "" {
-		loadRom(romName)
+		err = loadRom(romName)
 	} else if romFile != nil {
-		loadRomData(romFile)
+		err = loadRomData(romFile)
 	} else {
-		fmt.Println("no rom data provided during Start call")
+		err = errors.New("no rom data provided during Start call")
+	}
+	if err != nil {
+		CloseInterpreter()
+		return err
 	}
 	tryStartInterpreter()
+	return nil
+}
+
+// assembleOcto assembles Octo source into a ROM, with any error pointing at the file, line, and column
+func assembleOcto(reader fyne.URIReadCloser) ([]byte, error) {
+	defer reader.Close()
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return cli.AssembleSource(reader.URI().Name(), source)
 }
 
 func CloseInterpreter() {
-	resetInterpreter(MODE_NONE)
+	resetInterpreter(chip8.MODE_NONE)
 	tryCloseDisplay()
 }
 
+// RunAppWithRom opens the app with a ROM given on the command line already running, and its settings selected.
+// The interpreter's progress is logged to the terminal, so that a fault is visible there too
+func RunAppWithRom(startup cli.Startup) error {
+	selectedInterpreterMode = startup.Mode
+	selectedQuirks = startup.Quirks
+	selectedSeed = startup.Seed
+	setInstructionsPerFrame(startup.InstructionsPerFrame)
+	startupRom = io.NopCloser(bytes.NewReader(startup.Rom))
+	startupRomName = startup.Name
+	interpreterLifecycle.Subscribe(func(event lifecycle.Event) {
+		fmt.Fprintf(os.Stderr, "Interpreter %v\n", event.To)
+	})
+	RunApp()
+	return nil
+}
+
 func RunApp() {
 	fyneApp := app.New()
 	fyneWindow := fyneApp.NewWindow("CHIP-8 Controller")
 	fyneWindow.Resize(fyne.NewSize(600, 500))
 
+	// startRom starts a ROM in the background with the selected settings, showing a dialog if it fails to load.
+	// It's called on the UI thread, which is where the settings are read
+	startRom := func(name string, romName string, romFile RomFileReader) {
+		settings := selectedRomSettings()
+		go func() {
+			err := StartRom(settings, romName, romFile)
+			if err != nil {
+				fyne.Do(func() { dialog.ShowError(fmt.Errorf("%s: %w", name, err), fyneWindow) })
+			}
+		}()
+	}
+
 	loadRomMenu := fyne.NewMenuItem("Load ROM", nil)
-	loadRomMenu.ChildMenu = fyne.NewMenu("",
-		fyne.NewMenuItem("Test Suite 1", func() { go StartRom("testsuite1", nil) }),
-		fyne.NewMenuItem("Test Suite 2", func() { go StartRom("testsuite2", nil) }),
-		fyne.NewMenuItem("Test Suite 3", func() { go StartRom("testsuite3", nil) }),
-		fyne.NewMenuItem("Test Suite 4", func() { go StartRom("testsuite4", nil) }),
-		fyne.NewMenuItem("Test Suite 5", func() { go StartRom("testsuite5", nil) }),
-		fyne.NewMenuItem("Test Suite 6", func() { go StartRom("testsuite6", nil) }),
-		fyne.NewMenuItem("Octojam - Title 9", func() { go StartRom("octojam9title", nil) }),
-	)
 	fileMenu := fyne.NewMenu("CHIP-8",
 		fyne.NewMenuItem("Open File", func() {
 			fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
-				if reader != nil {
-					go StartRom("", reader)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("%w: %w", chip8.ErrRomRead, err), fyneWindow)
+					return
 				}
+				if reader == nil {
+					return
+				}
+				if reader.URI().Extension() == ".8o" {
+					// Octo source is assembled into a ROM first
+					rom, err := assembleOcto(reader)
+					if err != nil {
+						dialog.ShowError(err, fyneWindow)
+						return
+					}
+					startRom(reader.URI().Name(), "", io.NopCloser(bytes.NewReader(rom)))
+					return
+				}
+				startRom(reader.URI().Name(), "", reader)
 			}, fyneWindow)
-			// Only allow reading of CHIP-8 rom files
-			fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".ch8"}))
+			// Only allow reading of CHIP-8 rom files and Octo source
+			fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".ch8", ".8o"}))
 
+			// Start in the working directory where possible, otherwise leave the dialog at its default location
 			curDir, err := os.Getwd()
-			if err != nil {
-				panic(err)
-			}
-			listableURI, err := storage.ListerForURI(storage.NewFileURI(curDir))
-			if err != nil {
-				panic(err)
+			if err == nil {
+				listableURI, err := storage.ListerForURI(storage.NewFileURI(curDir))
+				if err == nil {
+					fileDialog.SetLocation(listableURI)
+				}
 			}
-			fileDialog.SetLocation(listableURI)
 			fileDialog.Show()
 		}),
 		loadRomMenu,
+		fyne.NewMenuItemSeparator(),
+		newStateSlotMenu("Save State", saveStateSlot, fyneWindow),
+		newStateSlotMenu("Load State", loadStateSlot, fyneWindow),
+		fyne.NewMenuItemSeparator(),
 		fyne.NewMenuItem("Close Interpreter", func() { go CloseInterpreter() }),
 	)
 
+	quirksMenu, refreshQuirksMenu := newQuirksMenu()
 	selectModeMenu := fyne.NewMenuItem("Hardware Mode", nil)
 
-	selectMode := func(mode InterpreterMode) {
+	selectMode := func(mode chip8.InterpreterMode) {
 		selectModeMenu.ChildMenu.Items[selectedInterpreterMode-1].Checked = false
+		modeQuirks[selectedInterpreterMode] = selectedQuirks
 		selectedInterpreterMode = mode
 		selectModeMenu.ChildMenu.Items[mode-1].Checked = true
+
+		// Each mode keeps its own quirks, starting from its own platform's until they're changed
+		quirks, ok := modeQuirks[mode]
+		if !ok {
+			quirks = chip8.DefaultQuirks(mode)
+		}
+		selectedQuirks = quirks
+		refreshQuirksMenu()
 	}
 	selectModeMenu.ChildMenu = fyne.NewMenu("",
-		fyne.NewMenuItem("CHIP-8", func() { selectMode(MODE_CHIP8) }),
-		fyne.NewMenuItem("SUPER-CHIP", func() { selectMode(MODE_SUPERCHIP) }),
-		fyne.NewMenuItem("XO-CHIP", func() { selectMode(MODE_XOCHIP) }),
+		fyne.NewMenuItem("CHIP-8", func() { selectMode(chip8.MODE_CHIP8) }),
+		fyne.NewMenuItem("SUPER-CHIP", func() { selectMode(chip8.MODE_SUPERCHIP) }),
+		fyne.NewMenuItem("XO-CHIP", func() { selectMode(chip8.MODE_XOCHIP) }),
 	)
-	selectModeMenu.ChildMenu.Items[0].Checked = true
-	selectModeMenu.ChildMenu.Items[1].Disabled = true
-	selectModeMenu.ChildMenu.Items[2].Disabled = true
+	selectModeMenu.ChildMenu.Items[selectedInterpreterMode-1].Checked = true
+	loadRomMenu.ChildMenu = newLoadRomMenu(selectMode, startRom)
 	optionsMenu := fyne.NewMenu("Options",
 		selectModeMenu,
+		quirksMenu,
+		fyne.NewMenuItem("Random Seed...", func() { showSeedDialog(fyneWindow) }),
+		newSoundMenu(),
+		newRewindMenu(),
 	)
 	mainMenu := fyne.NewMainMenu(
 		fileMenu,
+		newEmulationMenu(),
+		newDebugMenu(fyneWindow),
 		optionsMenu,
 	)
+	onFault = func(fault *chip8.Fault) {
+		fyne.Do(func() { showFaultDialog(fault, fyneWindow) })
+	}
 	fyneWindow.SetMainMenu(mainMenu)
+	inspectorClosed := make(chan bool)
+	fyneWindow.SetContent(newInspectorPanel(inspectorClosed))
+	fyneWindow.SetOnClosed(func() { close(inspectorClosed) })
 	fyneWindow.Show()
 
 	// Setup SDL Display
 	initialiseWindow()
 	defer sdl.Quit()
 	defer window.Destroy()
+	err := initialiseAudio()
+	if err != nil {
+		fmt.Println("failed to open audio device, sound is disabled:", err)
+	}
+	defer closeAudio()
 
+	if startupRom != nil {
+		startRom(startupRomName, "", startupRom)
+	}
 	fyneApp.Run()
 	go CloseInterpreter()
 }
+
+// newLoadRomMenu builds the menu of ROMs in the built in library. Each ROM is started in the mode it was written for
+func newLoadRomMenu(selectMode func(chip8.InterpreterMode), startRom func(string, string, RomFileReader)) *fyne.Menu {
+	library := roms.Library()
+	if len(library) == 0 {
+		noRomsItem := fyne.NewMenuItem("No ROMs Built In", nil)
+		noRomsItem.Disabled = true
+		return fyne.NewMenu("", noRomsItem)
+	}
+	loadRomMenu := fyne.NewMenu("")
+	for _, rom := range library {
+		loadRomMenu.Items = append(loadRomMenu.Items, fyne.NewMenuItem(rom.Label(), func() {
+			selectMode(rom.Mode)
+			startRom(rom.Title, rom.Name, nil)
+		}))
+	}
+	return loadRomMenu
+}
+
+// newQuirksMenu builds a menu of toggles for selectedQuirks, which apply immediately to the running ROM.
+// The returned function re-syncs the menu after selectedQuirks is changed elsewhere
+func newQuirksMenu() (*fyne.MenuItem, func()) {
+	quirkToggles := []struct {
+		label string
+		value *bool
+	}{
+		{"VF Reset (8XY1, 8XY2, 8XY3)", &selectedQuirks.VFReset},
+		{"Shift VX In Place (8XY6, 8XYE)", &selectedQuirks.Shifting},
+		{"Increment I (FX55, FX65)", &selectedQuirks.MemoryIncrement},
+		{"Display Wait (DXYN)", &selectedQuirks.DisplayWait},
+		{"Clip Sprites (DXYN)", &selectedQuirks.Clipping},
+		{"Jump With VX (BXNN)", &selectedQuirks.Jumping},
+		{"Wait For Key Release (FX0A)", &selectedQuirks.KeyRelease},
+		{"Fault On Out Of Range Memory", &selectedQuirks.MemoryFault},
+	}
+
+	stackDepths := []struct {
+		label string
+		depth int
+	}{
+		{fmt.Sprintf("%d (COSMAC VIP)", chip8.STACK_DEPTH_COSMAC), chip8.STACK_DEPTH_COSMAC},
+		{fmt.Sprintf("%d (SUPER-CHIP)", chip8.STACK_DEPTH_SUPERCHIP), chip8.STACK_DEPTH_SUPERCHIP},
+		{"Unlimited", 0},
+	}
+
+	quirksMenu := fyne.NewMenuItem("Quirks", nil)
+	quirksMenu.ChildMenu = fyne.NewMenu("")
+	stackDepthMenu := fyne.NewMenuItem("Stack Depth", nil)
+	stackDepthMenu.ChildMenu = fyne.NewMenu("")
+	refreshQuirksMenu := func() {
+		for i, toggle := range quirkToggles {
+			quirksMenu.ChildMenu.Items[i].Checked = *toggle.value
+		}
+		// A depth given on the command line may not be any of the choices, in which case none are checked
+		for i, choice := range stackDepths {
+			stackDepthMenu.ChildMenu.Items[i].Checked = choice.depth == selectedQuirks.StackDepth
+		}
+	}
+
+	for _, toggle := range quirkToggles {
+		quirksMenu.ChildMenu.Items = append(quirksMenu.ChildMenu.Items, fyne.NewMenuItem(toggle.label, func() {
+			*toggle.value = !*toggle.value
+			machine.SetQuirks(selectedQuirks)
+			refreshQuirksMenu()
+		}))
+	}
+	for _, choice := range stackDepths {
+		stackDepthMenu.ChildMenu.Items = append(stackDepthMenu.ChildMenu.Items, fyne.NewMenuItem(choice.label, func() {
+			selectedQuirks.StackDepth = choice.depth
+			machine.SetQuirks(selectedQuirks)
+			refreshQuirksMenu()
+		}))
+	}
+	quirksMenu.ChildMenu.Items = append(quirksMenu.ChildMenu.Items,
+		stackDepthMenu,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Reset To Mode Defaults", func() {
+			selectedQuirks = chip8.DefaultQuirks(selectedInterpreterMode)
+			machine.SetQuirks(selectedQuirks)
+			refreshQuirksMenu()
+		}),
+	)
+	refreshQuirksMenu()
+	return quirksMenu, refreshQuirksMenu
+}
+
+// newStateSlotMenu builds a submenu with an item per save state slot, showing any errors in a dialog
+func newStateSlotMenu(label string, slotAction func(int) error, fyneWindow fyne.Window) *fyne.MenuItem {
+	slotMenu := fyne.NewMenuItem(label, nil)
+	slotMenu.ChildMenu = fyne.NewMenu("")
+	for slot := 1; slot <= SAVE_STATE_SLOTS; slot++ {
+		slotMenu.ChildMenu.Items = append(slotMenu.ChildMenu.Items, fyne.NewMenuItem(fmt.Sprintf("Slot %d", slot), func() {
+			err := slotAction(slot)
+			if err != nil {
+				dialog.ShowError(err, fyneWindow)
+			}
+		}))
+	}
+	return slotMenu
+}
+
+// newEmulationMenu builds the menu for controlling the interpreter's speed, mirroring the SDL window's hotkeys
+func newEmulationMenu() *fyne.Menu {
+	speedMenu := fyne.NewMenuItem("Instructions Per Frame", nil)
+	speedMenu.ChildMenu = fyne.NewMenu("")
+	for _, preset := range speedPresets {
+		speedMenu.ChildMenu.Items = append(speedMenu.ChildMenu.Items,
+			fyne.NewMenuItem(strconv.Itoa(preset), func() { setInstructionsPerFrame(preset) }))
+	}
+
+	pauseItem := fyne.NewMenuItem("Pause (P)", togglePause)
+	turboItem := fyne.NewMenuItem("Turbo (Hold Tab)", nil)
+	turboItem.Action = func() { setTurbo(!turboItem.Checked) }
+	slowMotionItem := fyne.NewMenuItem("Slow Motion (M)", toggleSlowMotion)
+	emulationMenu := fyne.NewMenu("Emulation",
+		pauseItem,
+		fyne.NewMenuItem("Advance Frame (N)", advanceFrame),
+		fyne.NewMenuItemSeparator(),
+		speedMenu,
+		turboItem,
+		slowMotionItem,
+	)
+
+	refreshEmulationMenu := func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		for i, preset := range speedPresets {
+			speedMenu.ChildMenu.Items[i].Checked = preset == instructionsPerFrame
+		}
+		pauseItem.Checked = isPaused
+		turboItem.Checked = isTurbo
+		slowMotionItem.Checked = isSlowMotion
+	}
+	refreshEmulationMenu()
+	// Speed settings can also change from the SDL window's hotkeys, so hop back onto the UI thread
+	onSpeedChanged = func() {
+		fyne.Do(func() {
+			refreshEmulationMenu()
+			emulationMenu.Refresh()
+		})
+	}
+	return emulationMenu
+}
+
+// newChoiceMenu builds a submenu where only the most recently selected item is checked
+func newChoiceMenu(label string, choices []string, selected int, onSelect func(int)) *fyne.MenuItem {
+	choiceMenu := fyne.NewMenuItem(label, nil)
+	choiceMenu.ChildMenu = fyne.NewMenu("")
+	for i, choice := range choices {
+		choiceMenu.ChildMenu.Items = append(choiceMenu.ChildMenu.Items, fyne.NewMenuItem(choice, func() {
+			for _, item := range choiceMenu.ChildMenu.Items {
+				item.Checked = false
+			}
+			choiceMenu.ChildMenu.Items[i].Checked = true
+			onSelect(i)
+		}))
+	}
+	choiceMenu.ChildMenu.Items[selected].Checked = true
+	return choiceMenu
+}
+
+// newSoundMenu builds the menus for configuring the buzzer's waveform, frequency, and volume
+func newSoundMenu() *fyne.MenuItem {
+	waveforms := []sound.Waveform{sound.WAVEFORM_SQUARE, sound.WAVEFORM_SINE, sound.WAVEFORM_TRIANGLE}
+	frequencies := []float64{220, 440, 880}
+	volumes := []float64{0, 0.25, 0.5, 0.75, 1}
+
+	soundMenu := fyne.NewMenuItem("Sound", nil)
+	soundMenu.ChildMenu = fyne.NewMenu("",
+		newChoiceMenu("Waveform", []string{"Square", "Sine", "Triangle"}, 0,
+			func(i int) { setBuzzerWaveform(waveforms[i]) }),
+		newChoiceMenu("Frequency", []string{"220 Hz", "440 Hz", "880 Hz"}, 1,
+			func(i int) { setBuzzerFrequency(frequencies[i]) }),
+		newChoiceMenu("Volume", []string{"Mute", "25%", "50%", "75%", "100%"}, 1,
+			func(i int) { setBuzzerVolume(volumes[i]) }),
+	)
+	return soundMenu
+}
+
+// newRewindMenu builds the menu for choosing how many seconds of rewind history to keep
+func newRewindMenu() *fyne.MenuItem {
+	choices := make([]string, len(rewindSecondsPresets))
+	selected := 0
+	for i, seconds := range rewindSecondsPresets {
+		choices[i] = fmt.Sprintf("%d Seconds", seconds)
+		if seconds == DEFAULT_REWIND_SECONDS {
+			selected = i
+		}
+	}
+	return newChoiceMenu("Rewind History", choices, selected, func(i int) {
+		setRewindSeconds(rewindSecondsPresets[i])
+	})
+}
+
+// showSeedDialog shows the current random seed, and asks for the seed to start each ROM with from now on.
+// Leaving it blank picks a new seed for every ROM
+func showSeedDialog(fyneWindow fyne.Window) {
+	seedEntry := widget.NewEntry()
+	seedEntry.SetPlaceHolder("New seed for every ROM")
+	if selectedSeed != nil {
+		seedEntry.SetText(strconv.FormatUint(*selectedSeed, 10))
+	}
+	dialog.ShowForm("Random Seed", "OK", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Current Seed", widget.NewLabel(strconv.FormatUint(machine.Seed(), 10))),
+		widget.NewFormItem("Start ROMs With", seedEntry),
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if strings.TrimSpace(seedEntry.Text) == "" {
+			selectedSeed = nil
+			return
+		}
+		seed, err := strconv.ParseUint(strings.TrimSpace(seedEntry.Text), 10, 64)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%q is not a seed, which must be a whole number", seedEntry.Text), fyneWindow)
+			return
+		}
+		selectedSeed = &seed
+	}, fyneWindow)
+}
+
+// newDebugMenu builds the menu for pausing, stepping, and breakpoints.
+// Most items also have a keyboard shortcut in the controller window, with stepping and breakpoints
+// matching the SDL window's hotkeys
+func newDebugMenu(fyneWindow fyne.Window) *fyne.Menu {
+	newShortcutItem := func(label string, key fyne.KeyName, modifier fyne.KeyModifier, action func()) *fyne.MenuItem {
+		item := fyne.NewMenuItem(label, action)
+		shortcut := &desktop.CustomShortcut{KeyName: key, Modifier: modifier}
+		item.Shortcut = shortcut
+		fyneWindow.Canvas().AddShortcut(shortcut, func(fyne.Shortcut) { action() })
+		return item
+	}
+	// askForAddress prompts for a hexadecimal address, then passes it to onAddress
+	askForAddress := func(title string, onAddress func(uint16)) {
+		addressEntry := widget.NewEntry()
+		addressEntry.SetPlaceHolder("0x200")
+		dialog.ShowForm(title, "OK", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Address", addressEntry),
+		}, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			address, err := parseAddress(addressEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, fyneWindow)
+				return
+			}
+			onAddress(address)
+		}, fyneWindow)
+	}
+
+	var traceItem *fyne.MenuItem
+	traceItem = fyne.NewMenuItem("Trace Instructions", func() {
+		traceItem.Checked = !traceItem.Checked
+		setTracing(traceItem.Checked)
+	})
+
+	return fyne.NewMenu("Debug",
+		newShortcutItem("Pause", fyne.KeyF6, 0, func() { setPaused(true) }),
+		newShortcutItem("Continue", fyne.KeyF5, 0, resumeInterpreter),
+		fyne.NewMenuItemSeparator(),
+		newShortcutItem("Step Instruction", fyne.KeyF11, 0, stepInstruction),
+		newShortcutItem("Step Over", fyne.KeyF10, 0, stepOver),
+		newShortcutItem("Step Out", fyne.KeyF11, fyne.KeyModifierShift, stepOut),
+		fyne.NewMenuItem("Run To Address...", func() {
+			askForAddress("Run To Address", runToAddress)
+		}),
+		fyne.NewMenuItemSeparator(),
+		newShortcutItem("Toggle Breakpoint At PC", fyne.KeyF9, 0, func() { toggleBreakpoint(machine.PC()) }),
+		fyne.NewMenuItem("Toggle Breakpoint...", func() {
+			askForAddress("Toggle Breakpoint", toggleBreakpoint)
+		}),
+		fyne.NewMenuItem("Clear Breakpoints", func() { machine.ClearBreakpoints() }),
+		fyne.NewMenuItemSeparator(),
+		traceItem,
+		fyne.NewMenuItem("Memory Viewer", showMemoryViewer),
+		fyne.NewMenuItem("Out Of Range Memory Accesses", func() {
+			dialog.ShowInformation("Out Of Range Memory Accesses", outOfRangeAccessesText(), fyneWindow)
+		}),
+	)
+}
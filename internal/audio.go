@@ -0,0 +1,113 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/greenrock64/chip8-interpreter/internal/sound"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	AUDIO_BYTES_PER_SAMPLE = 4
+	// Keep a couple of frames of audio queued, so that the buzzer doesn't crackle between frames
+	AUDIO_QUEUED_FRAMES = 2
+)
+
+var (
+	audioDevice sdl.AudioDeviceID
+	isAudioOpen bool
+
+	audioMutex sync.Mutex
+	buzzer     = sound.NewBuzzer()
+)
+
+// initialiseAudio opens an SDL audio device for the buzzer.
+// SDL's dummy driver can be selected with SDL_AUDIODRIVER=dummy to run without sound hardware
+func initialiseAudio() error {
+	err := sdl.InitSubSystem(sdl.INIT_AUDIO)
+	if err != nil {
+		return err
+	}
+	desired := sdl.AudioSpec{
+		Freq:     sound.SAMPLE_RATE,
+		Format:   sdl.AUDIO_F32SYS,
+		Channels: 1,
+		Samples:  1024,
+	}
+	audioDevice, err = sdl.OpenAudioDevice("", false, &desired, nil, 0)
+	if err != nil {
+		return err
+	}
+	isAudioOpen = true
+	fmt.Printf("Opened audio device using the %s driver\n", sdl.GetCurrentAudioDriver())
+	sdl.PauseAudioDevice(audioDevice, false)
+	return nil
+}
+
+func closeAudio() {
+	if isAudioOpen {
+		sdl.CloseAudioDevice(audioDevice)
+		isAudioOpen = false
+	}
+}
+
+// updateAudio tops up the audio queue while the sound timer is active. Should be called once per frame
+func updateAudio() {
+	if !isAudioOpen {
+		return
+	}
+	state := sound.StateOf(machine)
+	if state.SoundTimer == 0 {
+		sdl.ClearQueuedAudio(audioDevice)
+		return
+	}
+
+	targetSize := AUDIO_QUEUED_FRAMES * sound.SAMPLE_RATE / DISPLAY_REFRESH_RATE * AUDIO_BYTES_PER_SAMPLE
+	queuedSize := int(sdl.GetQueuedAudioSize(audioDevice))
+	if queuedSize >= targetSize {
+		return
+	}
+
+	samples := generateSamples((targetSize-queuedSize)/AUDIO_BYTES_PER_SAMPLE, state)
+	err := sdl.QueueAudio(audioDevice, samples)
+	if err != nil {
+		fmt.Println("failed to queue audio:", err)
+	}
+}
+
+// generateSamples produces count samples of the buzzer for the machine's sound state, as native-endian
+// 32-bit floats ready to queue
+func generateSamples(count int, state sound.State) []byte {
+	audioMutex.Lock()
+	samples := buzzer.Samples(count, state)
+	audioMutex.Unlock()
+
+	data := make([]byte, len(samples)*AUDIO_BYTES_PER_SAMPLE)
+	for i, sample := range samples {
+		binary.NativeEndian.PutUint32(data[i*AUDIO_BYTES_PER_SAMPLE:], math.Float32bits(sample))
+	}
+	return data
+}
+
+func setBuzzerWaveform(waveform sound.Waveform) {
+	audioMutex.Lock()
+	defer audioMutex.Unlock()
+	buzzer.Waveform = waveform
+}
+
+func setBuzzerFrequency(frequency float64) {
+	audioMutex.Lock()
+	defer audioMutex.Unlock()
+	buzzer.Frequency = frequency
+}
+
+func setBuzzerVolume(volume float64) {
+	audioMutex.Lock()
+	defer audioMutex.Unlock()
+	buzzer.Volume = volume
+}
@@ -0,0 +1,210 @@
+package chip8
+
+import (
+	"io"
+	"math"
+	"slices"
+)
+
+type StopReason int
+
+const (
+	// STOP_NONE means the frame ran to completion
+	STOP_NONE StopReason = iota
+	// STOP_BREAKPOINT means execution stopped before running an instruction at a breakpoint
+	STOP_BREAKPOINT
+	// STOP_TARGET means a step over, step out, or run to address target was reached
+	STOP_TARGET
+	// STOP_FAULT means an instruction couldn't be executed, see Machine.Fault
+	STOP_FAULT
+)
+
+// runTarget describes where execution should stop for step over, step out, and run to address
+type runTarget struct {
+	pc      uint16
+	matchPC bool
+	// maxStackDepth is the deepest the stack can be for the target to be reached
+	maxStackDepth int
+}
+
+// reached reports whether the machine has arrived at the target. The caller must hold m.mu
+func (target runTarget) reached(m *Machine) bool {
+	if target.matchPC && m.pc != target.pc {
+		return false
+	}
+	return len(m.stack.stack) <= target.maxStackDepth
+}
+
+// checkStop decides whether execution should stop before the next instruction. The caller must hold m.mu
+func (m *Machine) checkStop() StopReason {
+	skipBreakpoint := m.skipBreakpointCheck
+	m.skipBreakpointCheck = false
+
+	if m.runTarget != nil && m.runTarget.reached(m) {
+		m.runTarget = nil
+		m.skipBreakpointCheck = true
+		return STOP_TARGET
+	}
+	if !skipBreakpoint && m.breakpoints[m.pc] {
+		// Don't stop on this breakpoint again when execution resumes
+		m.skipBreakpointCheck = true
+		return STOP_BREAKPOINT
+	}
+	return STOP_NONE
+}
+
+// PC returns the address of the next instruction to be executed
+func (m *Machine) PC() uint16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pc
+}
+
+// Registers is a snapshot of the CPU registers, timers, and stack, for display in a debugger
+type Registers struct {
+	V          [16]uint8
+	I          uint16
+	PC         uint16
+	DelayTimer uint8
+	SoundTimer uint8
+	Stack      []uint16
+	// Next is the instruction at PC, which will be executed next
+	Next Instruction
+}
+
+// Registers returns a snapshot of the CPU registers, timers, and stack
+func (m *Machine) Registers() Registers {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registers := Registers{
+		I:          m.indexRegister,
+		PC:         m.pc,
+		DelayTimer: m.delayTimer,
+		SoundTimer: m.soundTimer,
+		Stack:      append([]uint16(nil), m.stack.stack...),
+		Next:       DecodeAt(m.memory, m.pc),
+	}
+	copy(registers.V[:], m.registers)
+	return registers
+}
+
+// SetRegisters overwrites the CPU registers and timers. The stack and next instruction are left untouched
+func (m *Machine) SetRegisters(registers Registers) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copy(m.registers, registers.V[:])
+	m.indexRegister = registers.I
+	m.pc = registers.PC
+	m.delayTimer = registers.DelayTimer
+	m.soundTimer = registers.SoundTimer
+	// The debugger has moved things around, so don't immediately stop on a breakpoint at the new PC
+	m.skipBreakpointCheck = true
+}
+
+// SetBreakpoint adds or removes a breakpoint at an address. Breakpoints are kept across resets
+func (m *Machine) SetBreakpoint(address uint16, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled {
+		m.breakpoints[address] = true
+	} else {
+		delete(m.breakpoints, address)
+	}
+}
+
+// HasBreakpoint reports whether there is a breakpoint at an address
+func (m *Machine) HasBreakpoint(address uint16) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.breakpoints[address]
+}
+
+// Breakpoints returns the addresses of every breakpoint, in ascending order
+func (m *Machine) Breakpoints() []uint16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	breakpoints := make([]uint16, 0, len(m.breakpoints))
+	for address := range m.breakpoints {
+		breakpoints = append(breakpoints, address)
+	}
+	slices.Sort(breakpoints)
+	return breakpoints
+}
+
+// ClearBreakpoints removes every breakpoint
+func (m *Machine) ClearBreakpoints() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.breakpoints)
+}
+
+// StepOver runs the next instruction, treating a 2NNN subroutine call as a single instruction.
+// Returns true if the call will instead be run by RunFrame, which stops with STOP_TARGET once it returns
+func (m *Machine) StepOver() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if DecodeAt(m.memory, m.pc).Op == OP_CALL {
+		m.runTarget = &runTarget{
+			pc:            m.pc + 2,
+			matchPC:       true,
+			maxStackDepth: len(m.stack.stack),
+		}
+		m.skipBreakpointCheck = true
+		return true
+	}
+	m.debugStep()
+	return false
+}
+
+// StepOut sets RunFrame to stop with STOP_TARGET once the current subroutine returns.
+// Returns false if there is no subroutine to return from
+func (m *Machine) StepOut() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.stack.stack) == 0 {
+		return false
+	}
+	m.runTarget = &runTarget{maxStackDepth: len(m.stack.stack) - 1}
+	m.skipBreakpointCheck = true
+	return true
+}
+
+// RunTo sets RunFrame to stop with STOP_TARGET once execution reaches an address
+func (m *Machine) RunTo(address uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runTarget = &runTarget{
+		pc:            address,
+		matchPC:       true,
+		maxStackDepth: math.MaxInt,
+	}
+	m.skipBreakpointCheck = true
+}
+
+// CancelRunTarget discards any pending step over, step out, or run to address target
+func (m *Machine) CancelRunTarget() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runTarget = nil
+}
+
+// SetTrace logs the address, opcode, and disassembly of every instruction executed to w.
+// A nil writer turns tracing off
+func (m *Machine) SetTrace(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trace = w
+}
+
+// debugStep executes a single instruction on behalf of the debugger.
+// A single step always counts as the start of a new frame, so a sprite draw waiting on the display goes ahead.
+// The caller must hold m.mu
+func (m *Machine) debugStep() {
+	m.vblank = true
+	m.step()
+	m.skipBreakpointCheck = true
+}
@@ -0,0 +1,346 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op identifies the operation an opcode performs, independent of its operands
+type Op int
+
+const (
+	OP_INVALID Op = iota
+	OP_CLS
+	OP_RET
+	OP_SCD
+	OP_SCU
+	OP_SCR
+	OP_SCL
+	OP_EXIT
+	OP_LOW
+	OP_HIGH
+	OP_JP
+	OP_CALL
+	OP_SE_VX_NN
+	OP_SNE_VX_NN
+	OP_SE_VX_VY
+	OP_SAVE_RANGE
+	OP_LOAD_RANGE
+	OP_LD_VX_NN
+	OP_ADD_VX_NN
+	OP_LD_VX_VY
+	OP_OR
+	OP_AND
+	OP_XOR
+	OP_ADD_VX_VY
+	OP_SUB
+	OP_SHR
+	OP_SUBN
+	OP_SHL
+	OP_SNE_VX_VY
+	OP_LD_I
+	OP_JP_V0
+	OP_RND
+	OP_DRW
+	OP_SKP
+	OP_SKNP
+	OP_LD_I_LONG
+	OP_PLANE
+	OP_AUDIO
+	OP_LD_VX_DT
+	OP_LD_VX_K
+	OP_LD_DT_VX
+	OP_LD_ST_VX
+	OP_ADD_I_VX
+	OP_LD_F_VX
+	OP_LD_HF_VX
+	OP_LD_B_VX
+	OP_PITCH
+	OP_LD_I_VX
+	OP_LD_VX_I
+	OP_LD_R_VX
+	OP_LD_VX_R
+)
+
+// opInfo describes how an Op is written in assembly, and the first platform to support it
+type opInfo struct {
+	mnemonic string
+	// operands are templates, in which {X}, {Y}, {N}, {NN}, {NNN}, and {LONG} are replaced by the decoded fields
+	operands []string
+	mode     InterpreterMode
+}
+
+var opInfos = map[Op]opInfo{
+	OP_INVALID:    {"DW", []string{"{OPCODE}"}, MODE_CHIP8},
+	OP_CLS:        {"CLS", nil, MODE_CHIP8},
+	OP_RET:        {"RET", nil, MODE_CHIP8},
+	OP_SCD:        {"SCD", []string{"{N}"}, MODE_SUPERCHIP},
+	OP_SCU:        {"SCU", []string{"{N}"}, MODE_XOCHIP},
+	OP_SCR:        {"SCR", nil, MODE_SUPERCHIP},
+	OP_SCL:        {"SCL", nil, MODE_SUPERCHIP},
+	OP_EXIT:       {"EXIT", nil, MODE_SUPERCHIP},
+	OP_LOW:        {"LOW", nil, MODE_SUPERCHIP},
+	OP_HIGH:       {"HIGH", nil, MODE_SUPERCHIP},
+	OP_JP:         {"JP", []string{"{NNN}"}, MODE_CHIP8},
+	OP_CALL:       {"CALL", []string{"{NNN}"}, MODE_CHIP8},
+	OP_SE_VX_NN:   {"SE", []string{"V{X}", "{NN}"}, MODE_CHIP8},
+	OP_SNE_VX_NN:  {"SNE", []string{"V{X}", "{NN}"}, MODE_CHIP8},
+	OP_SE_VX_VY:   {"SE", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SAVE_RANGE: {"SAVE", []string{"V{X}-V{Y}"}, MODE_XOCHIP},
+	OP_LOAD_RANGE: {"LOAD", []string{"V{X}-V{Y}"}, MODE_XOCHIP},
+	OP_LD_VX_NN:   {"LD", []string{"V{X}", "{NN}"}, MODE_CHIP8},
+	OP_ADD_VX_NN:  {"ADD", []string{"V{X}", "{NN}"}, MODE_CHIP8},
+	OP_LD_VX_VY:   {"LD", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_OR:         {"OR", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_AND:        {"AND", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_XOR:        {"XOR", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_ADD_VX_VY:  {"ADD", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SUB:        {"SUB", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SHR:        {"SHR", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SUBN:       {"SUBN", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SHL:        {"SHL", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_SNE_VX_VY:  {"SNE", []string{"V{X}", "V{Y}"}, MODE_CHIP8},
+	OP_LD_I:       {"LD", []string{"I", "{NNN}"}, MODE_CHIP8},
+	OP_JP_V0:      {"JP", []string{"V0", "{NNN}"}, MODE_CHIP8},
+	OP_RND:        {"RND", []string{"V{X}", "{NN}"}, MODE_CHIP8},
+	OP_DRW:        {"DRW", []string{"V{X}", "V{Y}", "{N}"}, MODE_CHIP8},
+	OP_SKP:        {"SKP", []string{"V{X}"}, MODE_CHIP8},
+	OP_SKNP:       {"SKNP", []string{"V{X}"}, MODE_CHIP8},
+	OP_LD_I_LONG:  {"LD", []string{"I", "{LONG}"}, MODE_XOCHIP},
+	OP_PLANE:      {"PLANE", []string{"{X}"}, MODE_XOCHIP},
+	OP_AUDIO:      {"AUDIO", nil, MODE_XOCHIP},
+	OP_LD_VX_DT:   {"LD", []string{"V{X}", "DT"}, MODE_CHIP8},
+	OP_LD_VX_K:    {"LD", []string{"V{X}", "K"}, MODE_CHIP8},
+	OP_LD_DT_VX:   {"LD", []string{"DT", "V{X}"}, MODE_CHIP8},
+	OP_LD_ST_VX:   {"LD", []string{"ST", "V{X}"}, MODE_CHIP8},
+	OP_ADD_I_VX:   {"ADD", []string{"I", "V{X}"}, MODE_CHIP8},
+	OP_LD_F_VX:    {"LD", []string{"F", "V{X}"}, MODE_CHIP8},
+	OP_LD_HF_VX:   {"LD", []string{"HF", "V{X}"}, MODE_SUPERCHIP},
+	OP_LD_B_VX:    {"LD", []string{"B", "V{X}"}, MODE_CHIP8},
+	OP_PITCH:      {"PITCH", []string{"V{X}"}, MODE_XOCHIP},
+	OP_LD_I_VX:    {"LD", []string{"[I]", "V{X}"}, MODE_CHIP8},
+	OP_LD_VX_I:    {"LD", []string{"V{X}", "[I]"}, MODE_CHIP8},
+	OP_LD_R_VX:    {"LD", []string{"R", "V{X}"}, MODE_SUPERCHIP},
+	OP_LD_VX_R:    {"LD", []string{"V{X}", "R"}, MODE_SUPERCHIP},
+}
+
+// fxOps are the FXNN operations, by NN
+var fxOps = map[uint8]Op{
+	0x07: OP_LD_VX_DT,
+	0x0A: OP_LD_VX_K,
+	0x15: OP_LD_DT_VX,
+	0x18: OP_LD_ST_VX,
+	0x1E: OP_ADD_I_VX,
+	0x29: OP_LD_F_VX,
+	0x30: OP_LD_HF_VX,
+	0x33: OP_LD_B_VX,
+	0x3A: OP_PITCH,
+	0x55: OP_LD_I_VX,
+	0x65: OP_LD_VX_I,
+	0x75: OP_LD_R_VX,
+	0x85: OP_LD_VX_R,
+}
+
+// SupportedIn reports whether the operation exists on the given platform.
+// XO-CHIP builds on SUPER-CHIP, which builds on CHIP-8
+func (op Op) SupportedIn(mode InterpreterMode) bool {
+	required := opInfos[op].mode
+	return op != OP_INVALID && (required == MODE_CHIP8 || mode >= required)
+}
+
+// Instruction is a decoded opcode, split into its operation and operand fields
+type Instruction struct {
+	Opcode uint16
+	Op     Op
+
+	X   uint8
+	Y   uint8
+	N   uint8
+	NN  uint8
+	NNN uint16
+	// Long is the 16-bit address following an F000 opcode
+	Long uint16
+}
+
+// Decode splits an opcode into an Instruction. The Long operand of F000 follows the opcode,
+// so it is left as zero; use DecodeAt to decode from memory instead
+func Decode(opcode uint16) Instruction {
+	ins := Instruction{
+		Opcode: opcode,
+		X:      uint8((opcode & 0x0F00) >> 8),
+		Y:      uint8((opcode & 0x00F0) >> 4),
+		N:      uint8(opcode & 0x000F),
+		NN:     uint8(opcode & 0x00FF),
+		NNN:    opcode & 0x0FFF,
+	}
+	ins.Op = decodeOp(ins)
+	return ins
+}
+
+// DecodeAt decodes the instruction at an address in memory, including the Long operand of F000.
+// Addresses past the end of memory wrap back around to the start, as they do when the Machine reads memory
+func DecodeAt(memory []byte, address uint16) Instruction {
+	if len(memory) == 0 {
+		return Decode(0)
+	}
+	readWord := func(address int) uint16 {
+		return uint16(memory[address%len(memory)])<<8 | uint16(memory[(address+1)%len(memory)])
+	}
+
+	ins := Decode(readWord(int(address)))
+	if ins.Op == OP_LD_I_LONG {
+		ins.Long = readWord(int(address) + 2)
+	}
+	return ins
+}
+
+// decodeOp works out which operation an opcode performs from its fields
+func decodeOp(ins Instruction) Op {
+	switch ins.Opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case ins.Opcode == 0x00E0:
+			return OP_CLS
+		case ins.Opcode == 0x00EE:
+			return OP_RET
+		case ins.Opcode&0xFFF0 == 0x00C0:
+			return OP_SCD
+		case ins.Opcode&0xFFF0 == 0x00D0:
+			return OP_SCU
+		case ins.Opcode == 0x00FB:
+			return OP_SCR
+		case ins.Opcode == 0x00FC:
+			return OP_SCL
+		case ins.Opcode == 0x00FD:
+			return OP_EXIT
+		case ins.Opcode == 0x00FE:
+			return OP_LOW
+		case ins.Opcode == 0x00FF:
+			return OP_HIGH
+		}
+	case 0x1000:
+		return OP_JP
+	case 0x2000:
+		return OP_CALL
+	case 0x3000:
+		return OP_SE_VX_NN
+	case 0x4000:
+		return OP_SNE_VX_NN
+	case 0x5000:
+		switch ins.N {
+		case 0x0:
+			return OP_SE_VX_VY
+		case 0x2:
+			return OP_SAVE_RANGE
+		case 0x3:
+			return OP_LOAD_RANGE
+		}
+	case 0x6000:
+		return OP_LD_VX_NN
+	case 0x7000:
+		return OP_ADD_VX_NN
+	case 0x8000:
+		switch ins.N {
+		case 0x0:
+			return OP_LD_VX_VY
+		case 0x1:
+			return OP_OR
+		case 0x2:
+			return OP_AND
+		case 0x3:
+			return OP_XOR
+		case 0x4:
+			return OP_ADD_VX_VY
+		case 0x5:
+			return OP_SUB
+		case 0x6:
+			return OP_SHR
+		case 0x7:
+			return OP_SUBN
+		case 0xE:
+			return OP_SHL
+		}
+	case 0x9000:
+		if ins.N == 0 {
+			return OP_SNE_VX_VY
+		}
+	case 0xA000:
+		return OP_LD_I
+	case 0xB000:
+		return OP_JP_V0
+	case 0xC000:
+		return OP_RND
+	case 0xD000:
+		return OP_DRW
+	case 0xE000:
+		switch ins.NN {
+		case 0x9E:
+			return OP_SKP
+		case 0xA1:
+			return OP_SKNP
+		}
+	case 0xF000:
+		switch {
+		case ins.Opcode == 0xF000:
+			return OP_LD_I_LONG
+		case ins.NN == 0x01:
+			return OP_PLANE
+		case ins.Opcode == 0xF002:
+			return OP_AUDIO
+		}
+		if op, ok := fxOps[ins.NN]; ok {
+			return op
+		}
+	}
+	return OP_INVALID
+}
+
+// Size returns the number of bytes the instruction takes up in memory
+func (ins Instruction) Size() uint16 {
+	if ins.Op == OP_LD_I_LONG {
+		return 4
+	}
+	return 2
+}
+
+// Target returns the address a JP or CALL instruction goes to.
+// Returns false for any other instruction, including JP V0 whose target depends on a register
+func (ins Instruction) Target() (uint16, bool) {
+	if ins.Op == OP_JP || ins.Op == OP_CALL {
+		return ins.NNN, true
+	}
+	return 0, false
+}
+
+// Mnemonic returns the assembly mnemonic of the instruction, e.g. "LD"
+func (ins Instruction) Mnemonic() string {
+	return opInfos[ins.Op].mnemonic
+}
+
+// Operands returns the assembly operands of the instruction, e.g. ["V1", "0x2A"]
+func (ins Instruction) Operands() []string {
+	replacer := strings.NewReplacer(
+		"{X}", fmt.Sprintf("%X", ins.X),
+		"{Y}", fmt.Sprintf("%X", ins.Y),
+		"{N}", fmt.Sprintf("%d", ins.N),
+		"{NN}", fmt.Sprintf("0x%02X", ins.NN),
+		"{NNN}", fmt.Sprintf("0x%03X", ins.NNN),
+		"{LONG}", fmt.Sprintf("0x%04X", ins.Long),
+		"{OPCODE}", fmt.Sprintf("0x%04X", ins.Opcode),
+	)
+	templates := opInfos[ins.Op].operands
+	operands := make([]string, len(templates))
+	for i, template := range templates {
+		operands[i] = replacer.Replace(template)
+	}
+	return operands
+}
+
+// String returns the instruction in assembly, e.g. "LD V1, 0x2A"
+func (ins Instruction) String() string {
+	operands := ins.Operands()
+	if len(operands) == 0 {
+		return ins.Mnemonic()
+	}
+	return ins.Mnemonic() + " " + strings.Join(operands, ", ")
+}
@@ -0,0 +1,85 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DisassembleRom writes a ROM out as annotated assembly, with the address and raw bytes of each instruction.
+// Jump and call targets within the ROM are given labels. Opcodes that the mode doesn't support are written as data.
+// As with LoadRom, the ROM must fit in the mode's memory
+func DisassembleRom(w io.Writer, rom []byte, mode InterpreterMode) error {
+	available := memorySize(mode) - MEM_ROM_START
+	if len(rom) > available {
+		return fmt.Errorf("%w: %d bytes, but only %d fit in memory", ErrRomTooLarge, len(rom), available)
+	}
+	decode := func(offset int) Instruction {
+		ins := DecodeAt(rom, uint16(offset))
+		if !ins.Op.SupportedIn(mode) {
+			ins = Instruction{Opcode: ins.Opcode, Op: OP_INVALID}
+		}
+		return ins
+	}
+
+	// Find where each instruction starts, so that only targets which line up with one get a label
+	starts := map[uint16]bool{}
+	for offset := 0; fits(rom, offset, decode(offset)); offset += int(decode(offset).Size()) {
+		starts[uint16(MEM_ROM_START+offset)] = true
+	}
+	labels := map[uint16]string{}
+	for address := range starts {
+		ins := decode(int(address) - MEM_ROM_START)
+		target, ok := ins.Target()
+		if !ok || !starts[target] {
+			continue
+		}
+		if ins.Op == OP_CALL {
+			labels[target] = fmt.Sprintf("sub_%03X", target)
+		} else if labels[target] == "" {
+			labels[target] = fmt.Sprintf("label_%03X", target)
+		}
+	}
+
+	var listing strings.Builder
+	fmt.Fprintf(&listing, "; %d bytes\n", len(rom))
+	offset := 0
+	for ; fits(rom, offset, decode(offset)); offset += int(decode(offset).Size()) {
+		address := uint16(MEM_ROM_START + offset)
+		ins := decode(offset)
+
+		if label, ok := labels[address]; ok {
+			fmt.Fprintf(&listing, "\n%s:\n", label)
+		}
+		raw := fmt.Sprintf("%04X", ins.Opcode)
+		if ins.Size() == 4 {
+			raw += fmt.Sprintf(" %04X", ins.Long)
+		}
+		text := ins.String()
+		if target, ok := ins.Target(); ok && labels[target] != "" {
+			text = ins.Mnemonic() + " " + labels[target]
+		}
+		fmt.Fprintf(&listing, "%04X  %-9s  %s\n", address, raw, text)
+	}
+	if offset < len(rom) {
+		// An odd trailing byte, or an F000 cut off before its address, can't be an instruction
+		rest := rom[offset:]
+		values := make([]string, len(rest))
+		for i, value := range rest {
+			values[i] = fmt.Sprintf("0x%02X", value)
+		}
+		text := "DB " + strings.Join(values, ", ")
+		if len(rest) > 1 {
+			text += " ; truncated F000, missing its address"
+		}
+		fmt.Fprintf(&listing, "%04X  %-9s  %s\n", MEM_ROM_START+offset, fmt.Sprintf("%X", rest), text)
+	}
+
+	_, err := io.WriteString(w, listing.String())
+	return err
+}
+
+// fits reports whether the whole of an instruction at offset lies within the ROM
+func fits(rom []byte, offset int, ins Instruction) bool {
+	return offset+int(ins.Size()) <= len(rom)
+}
@@ -0,0 +1,57 @@
+package chip8
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDisassembleTrailingBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		rom      []byte
+		expected string
+	}{
+		{"odd byte", []byte{0x00, 0xE0, 0x12}, "0202  12         DB 0x12\n"},
+		{"F000 without its address", []byte{0x00, 0xE0, 0xF0, 0x00}, "0202  F000       DB 0xF0, 0x00 ; truncated F000, missing its address\n"},
+		{"F000 with half its address", []byte{0x00, 0xE0, 0xF0, 0x00, 0x12}, "0202  F00012     DB 0xF0, 0x00, 0x12 ; truncated F000, missing its address\n"},
+		{"complete F000", []byte{0x00, 0xE0, 0xF0, 0x00, 0x12, 0x34}, "0202  F000 1234  LD I, 0x1234\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var listing strings.Builder
+			err := DisassembleRom(&listing, test.rom, MODE_XOCHIP)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(listing.String(), test.expected) {
+				t.Errorf("listing =\n%s\nexpected it to end with\n%s", listing.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestDisassembleTooLarge(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     InterpreterMode
+		size     int
+		expected error
+	}{
+		{"fits in CHIP-8", MODE_CHIP8, MEM_SIZE - MEM_ROM_START, nil},
+		{"too large for CHIP-8", MODE_CHIP8, MEM_SIZE - MEM_ROM_START + 1, ErrRomTooLarge},
+		{"too large for SUPER-CHIP", MODE_SUPERCHIP, MEM_SIZE - MEM_ROM_START + 1, ErrRomTooLarge},
+		{"fits in XO-CHIP", MODE_XOCHIP, MEM_SIZE_XOCHIP - MEM_ROM_START, nil},
+		{"too large for XO-CHIP", MODE_XOCHIP, MEM_SIZE_XOCHIP - MEM_ROM_START + 1, ErrRomTooLarge},
+		{"past 16-bit addresses", MODE_XOCHIP, 0x10000 + 2, ErrRomTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := DisassembleRom(io.Discard, make([]byte, test.size), test.mode)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("error = %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package chip8
+
+const (
+	DISPLAY_WIDTH        = 64
+	DISPLAY_HEIGHT       = 32
+	HIRES_DISPLAY_WIDTH  = 128
+	HIRES_DISPLAY_HEIGHT = 64
+
+	// Each display pixel holds one bit per XO-CHIP bitplane
+	PLANE_1    = 0x1
+	PLANE_2    = 0x2
+	PLANE_BOTH = PLANE_1 | PLANE_2
+)
+
+// displaySize returns the width and height of the display at the current resolution.
+// The caller must hold m.mu
+func (m *Machine) displaySize() (int, int) {
+	if m.hires {
+		return HIRES_DISPLAY_WIDTH, HIRES_DISPLAY_HEIGHT
+	}
+	return DISPLAY_WIDTH, DISPLAY_HEIGHT
+}
+
+// clearDisplay resets every pixel on every plane to off. The caller must hold m.mu
+func (m *Machine) clearDisplay() {
+	width, height := m.displaySize()
+	m.display = make([][]uint8, width)
+	for i := range m.display {
+		m.display[i] = make([]uint8, height)
+	}
+}
+
+// clearPlanes turns off every pixel on the selected planes. The caller must hold m.mu
+func (m *Machine) clearPlanes() {
+	for x := range m.display {
+		for y := range m.display[x] {
+			m.display[x][y] &^= m.planes
+		}
+	}
+}
+
+// setHires switches between the 64x32 and 128x64 resolutions, clearing the display.
+// The caller must hold m.mu
+func (m *Machine) setHires(hires bool) {
+	m.hires = hires
+	m.clearDisplay()
+}
+
+// drawSprite XORs a sprite from I onto each selected plane of the display.
+// An n of 0 draws a 16x16 sprite in SUPER-CHIP and XO-CHIP modes.
+// Sprites are clipped at the display edges, or wrap around them if the clipping quirk is disabled.
+// Returns the number of sprite rows that turned a pixel off, plus in SUPER-CHIP hires mode
+// the number of rows clipped off the bottom of the display. The caller must hold m.mu
+func (m *Machine) drawSprite(vx uint8, vy uint8, n uint8) int {
+	width, height := m.displaySize()
+	memPos := int(m.indexRegister)
+	wrap := !m.quirks.Clipping
+
+	spriteWidth, spriteHeight := m.spriteDimensions(n)
+
+	rowCount := 0
+	for _, plane := range []uint8{PLANE_1, PLANE_2} {
+		if m.planes&plane == 0 {
+			continue
+		}
+
+		posX := int(vx) % width
+		posY := int(vy) % height
+		for i := 0; i < spriteHeight; i++ {
+			if posY >= height {
+				if !wrap {
+					if m.mode == MODE_SUPERCHIP && m.hires {
+						// Each row lost off the bottom of the display counts as a collision
+						rowCount += spriteHeight - i
+					}
+					break
+				}
+				posY = 0
+			}
+
+			var sprite uint16
+			if spriteWidth == 16 {
+				sprite = uint16(m.readMemory(memPos))<<8 | uint16(m.readMemory(memPos+1))
+				memPos += 2
+			} else {
+				sprite = uint16(m.readMemory(memPos)) << 8
+				memPos++
+			}
+
+			didUnset := false
+			for x := 0; x < spriteWidth; x++ {
+				if sprite&(0x8000>>x) > 0 {
+					displayPosX := posX + (x)
+					if displayPosX >= width {
+						if !wrap {
+							break
+						}
+						displayPosX -= width
+					}
+					m.display[displayPosX][posY] ^= plane
+					if m.display[displayPosX][posY]&plane == 0 {
+						didUnset = true
+					}
+				}
+			}
+			if didUnset {
+				rowCount++
+			}
+
+			posY++
+		}
+	}
+	return rowCount
+}
+
+// spriteDimensions returns the width and height in pixels of a sprite drawn by DXYN. The caller must hold m.mu
+func (m *Machine) spriteDimensions(n uint8) (int, int) {
+	if n == 0 && m.supportsSuperChip() {
+		return 16, 16
+	}
+	return 8, int(n)
+}
+
+// spriteSize returns the number of bytes DXYN reads from memory, with the sprite for each selected plane
+// following on from the last. The caller must hold m.mu
+func (m *Machine) spriteSize(n uint8) int {
+	width, height := m.spriteDimensions(n)
+	size := 0
+	for _, plane := range []uint8{PLANE_1, PLANE_2} {
+		if m.planes&plane != 0 {
+			size += width / 8 * height
+		}
+	}
+	return size
+}
+
+// scroll moves the selected planes of the display right by dx and down by dy pixels.
+// Negative values scroll left and up. The caller must hold m.mu
+func (m *Machine) scroll(dx int, dy int) {
+	width, height := m.displaySize()
+	scrolled := make([][]uint8, width)
+	for x := range scrolled {
+		scrolled[x] = make([]uint8, height)
+		for y := range scrolled[x] {
+			// Pixels on unselected planes stay where they are
+			scrolled[x][y] = m.display[x][y] &^ m.planes
+			srcX, srcY := x-dx, y-dy
+			if srcX >= 0 && srcX < width && srcY >= 0 && srcY < height {
+				scrolled[x][y] |= m.display[srcX][srcY] & m.planes
+			}
+		}
+	}
+	m.display = scrolled
+}
+
+// Display returns a copy of the display, indexed as [x][y].
+// Each pixel holds a bitmask of the planes it is lit on, see PLANE_1 and PLANE_2.
+// The display is 128x64 in hires mode, and 64x32 otherwise
+func (m *Machine) Display() [][]uint8 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	display := make([][]uint8, len(m.display))
+	for x := range m.display {
+		display[x] = append([]uint8(nil), m.display[x]...)
+	}
+	return display
+}
+
+// SetKey updates the pressed state of one of the 16 keypad keys
+func (m *Machine) SetKey(key int, isPressed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.input[key] = isPressed
+}
+
+// Keys returns the pressed state of each of the 16 keypad keys
+func (m *Machine) Keys() []bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]bool(nil), m.input...)
+}
@@ -0,0 +1,90 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+type FaultKind int
+
+const (
+	// FAULT_UNKNOWN_OPCODE means the opcode isn't an instruction in the current mode
+	FAULT_UNKNOWN_OPCODE FaultKind = iota
+	// FAULT_STACK means a call overflowed the stack, or a return underflowed it
+	FAULT_STACK
+	// FAULT_MEMORY means an instruction accessed memory out of range, with the MemoryFault quirk set
+	FAULT_MEMORY
+	// FAULT_PANIC means the interpreter itself failed while executing the instruction
+	FAULT_PANIC
+)
+
+var (
+	ErrUnknownOpcode = errors.New("unknown opcode")
+	ErrPanic         = errors.New("interpreter error")
+)
+
+func (kind FaultKind) String() string {
+	switch kind {
+	case FAULT_UNKNOWN_OPCODE:
+		return "Unknown Opcode"
+	case FAULT_STACK:
+		return "Stack Fault"
+	case FAULT_MEMORY:
+		return "Memory Fault"
+	case FAULT_PANIC:
+		return "Interpreter Error"
+	}
+	return fmt.Sprintf("FaultKind(%d)", int(kind))
+}
+
+// Fault is an instruction that couldn't be executed, such as a 2NNN call with the stack already full.
+// A faulted Machine is left exactly as it was, with the PC pointing at the instruction, so it can be inspected
+// in the debugger. RunFrame won't run it again until the fault is ignored or cleared
+type Fault struct {
+	Kind   FaultKind
+	PC     uint16
+	Opcode uint16
+	Err    error
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%v at 0x%03X (%04X)", f.Err, f.PC, f.Opcode)
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+// Fault returns the fault the Machine is stopped on, or nil if it isn't faulted
+func (m *Machine) Fault() *Fault {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fault
+}
+
+// IgnoreFault skips over the faulting instruction, so that execution continues after it
+func (m *Machine) IgnoreFault() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fault == nil {
+		return
+	}
+	m.pc = m.fault.PC + m.instructionSize(m.fault.PC)
+	m.fault = nil
+	m.skipBreakpointCheck = true
+}
+
+// ClearFault leaves the PC at the faulting instruction, so that it runs again when execution continues,
+// presumably after the state that caused the fault has been fixed
+func (m *Machine) ClearFault() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fault = nil
+	m.skipBreakpointCheck = true
+}
+
+// raiseFault stops the current instruction, and winds the PC back to it. The caller must hold m.mu
+func (m *Machine) raiseFault(kind FaultKind, ins Instruction, address uint16, err error) {
+	m.pc = address
+	m.fault = &Fault{Kind: kind, PC: address, Opcode: ins.Opcode, Err: err}
+}
@@ -0,0 +1,96 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+)
+
+// newFaultingMachine loads a ROM that faults on an unknown opcode at 0x202
+func newFaultingMachine(t *testing.T) *Machine {
+	t.Helper()
+	m := New(MODE_CHIP8)
+	err := m.LoadRomBytes([]byte{0x61, 0x01, 0x80, 0x08, 0x71, 0x01, 0x12, 0x06})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFaultStopsRunFrame(t *testing.T) {
+	m := newFaultingMachine(t)
+	if reason := m.RunFrame(10); reason != STOP_FAULT {
+		t.Fatalf("RunFrame() = %d, expected STOP_FAULT", reason)
+	}
+	fault := m.Fault()
+	if fault == nil || fault.Kind != FAULT_UNKNOWN_OPCODE || fault.PC != 0x202 || fault.Opcode != 0x8008 {
+		t.Fatalf("fault = %+v, expected an unknown opcode 8008 at 0x202", fault)
+	}
+
+	// Nothing runs until the fault is dealt with
+	if reason := m.RunFrame(10); reason != STOP_FAULT {
+		t.Errorf("RunFrame() = %d, expected STOP_FAULT while faulted", reason)
+	}
+	if m.PC() != 0x202 || m.registers[1] != 1 {
+		t.Errorf("PC = 0x%03X and V1 = %d, expected the machine to be left at the fault", m.PC(), m.registers[1])
+	}
+}
+
+func TestIgnoreFault(t *testing.T) {
+	m := newFaultingMachine(t)
+	m.RunFrame(10)
+	m.IgnoreFault()
+	if m.Fault() != nil || m.PC() != 0x204 {
+		t.Fatalf("PC = 0x%03X, expected the faulting instruction to be skipped", m.PC())
+	}
+	if reason := m.RunFrame(2); reason != STOP_NONE || m.registers[1] != 2 {
+		t.Errorf("RunFrame() = %d with V1 = %d, expected execution to continue after the fault", reason, m.registers[1])
+	}
+}
+
+func TestClearFault(t *testing.T) {
+	m := newFaultingMachine(t)
+	m.RunFrame(10)
+	m.ClearFault()
+	if m.Fault() != nil || m.PC() != 0x202 {
+		t.Fatalf("PC = 0x%03X, expected to be left at the faulting instruction", m.PC())
+	}
+	// The instruction runs again, and faults again
+	if reason := m.RunFrame(10); reason != STOP_FAULT {
+		t.Errorf("RunFrame() = %d, expected STOP_FAULT", reason)
+	}
+}
+
+func TestPanicFault(t *testing.T) {
+	m := New(MODE_CHIP8)
+	// A display too small for the sprite makes DXYN index out of range
+	m.display = [][]uint8{{0}}
+	m.registers[1] = 5
+	m.memory[MEM_ROM_START] = 0xD1
+	m.memory[MEM_ROM_START+1] = 0x11
+	m.memory[0] = 0x80
+	m.Step()
+
+	fault := m.Fault()
+	if fault == nil || fault.Kind != FAULT_PANIC || !errors.Is(fault, ErrPanic) || fault.PC != 0x200 {
+		t.Fatalf("fault = %+v, expected a recovered panic at 0x200", fault)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	m := newFaultingMachine(t)
+	quirks := m.Quirks()
+	quirks.MemoryFault = true
+	m.SetQuirks(quirks)
+	m.RunFrame(10)
+
+	err := m.Restart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Fault() != nil || m.PC() != MEM_ROM_START || m.registers[1] != 0 || m.RomSize() != 8 {
+		t.Errorf("PC = 0x%03X and V1 = %d, expected the ROM to start again", m.PC(), m.registers[1])
+	}
+	if m.Quirks() != quirks {
+		t.Errorf("quirks = %+v, expected %+v to be kept", m.Quirks(), quirks)
+	}
+}
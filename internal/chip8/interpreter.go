@@ -0,0 +1,722 @@
+package chip8
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	MEM_SIZE                = 4096
+	MEM_SIZE_XOCHIP         = 65536
+	MEM_ROM_START           = 0x0200
+	MEM_FONT_DATA_START     = 0x0050
+	MEM_BIG_FONT_DATA_START = 0x00A0
+	MEM_FONT_DATA_END       = 0x0140 // End of the big font data, which follows the small font
+
+	RPL_FLAG_COUNT_SUPERCHIP = 8
+	RPL_FLAG_COUNT_XOCHIP    = 16
+
+	AUDIO_PATTERN_SIZE  = 16
+	AUDIO_DEFAULT_PITCH = 64
+)
+
+type InterpreterMode int
+
+const (
+	MODE_NONE InterpreterMode = iota
+	MODE_CHIP8
+	MODE_SUPERCHIP
+	MODE_XOCHIP
+)
+
+var fontData = []byte{
+	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+	0x20, 0x60, 0x20, 0x20, 0x70, // 1
+	0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+	0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+	0x90, 0x90, 0xF0, 0x10, 0x10, // 4
+	0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+	0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+	0xF0, 0x10, 0x20, 0x40, 0x40, // 7
+	0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+	0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+	0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+	0xE0, 0x90, 0xE0, 0x90, 0xE0, // B
+	0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+	0xE0, 0x90, 0x90, 0x90, 0xE0, // D
+	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
+}
+
+// bigFontData holds the 8x10 SUPER-CHIP digits
+var bigFontData = []byte{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
+// Machine holds the complete state of a single CHIP-8 interpreter.
+// All methods are safe to call from multiple goroutines.
+type Machine struct {
+	mu sync.Mutex
+
+	mode   InterpreterMode
+	quirks Quirks
+
+	memory        []byte
+	registers     []uint8
+	pc            uint16
+	indexRegister uint16
+	stack         Stack
+
+	delayTimer uint8
+	soundTimer uint8
+
+	display [][]uint8
+	planes  uint8
+	hires   bool
+	input   []bool
+
+	// vblank is set at the start of each frame, and consumed by a DXYN waiting on the display
+	vblank         bool
+	waitingOnFrame bool
+
+	audioPattern [AUDIO_PATTERN_SIZE]byte
+	pitch        uint8
+
+	// rplFlags persist across resets, like the HP-48 calculator's RPL user flags
+	rplFlags [16]uint8
+	halted   bool
+	romHash  string
+	romSize  int
+	// rom is a copy of the loaded ROM, for Restart
+	rom []byte
+
+	// seed starts the random number generator for CXNN
+	seed   uint64
+	random *rand.PCG
+
+	breakpoints         map[uint16]bool
+	runTarget           *runTarget
+	skipBreakpointCheck bool
+	// trace, if set, receives a line for every instruction executed
+	trace io.Writer
+
+	// fault is raised by the last instruction executed, if it couldn't be executed
+	fault *Fault
+	// outOfRange records instructions that accessed memory out of range, see OutOfRangeAccesses
+	outOfRange []OutOfRangeAccess
+
+	keyAwaitingRelease *int
+}
+
+// New creates a Machine that has been reset into the given mode
+func New(mode InterpreterMode) *Machine {
+	m := &Machine{
+		breakpoints: map[uint16]bool{},
+		seed:        NewSeed(),
+	}
+	m.Reset(mode)
+	return m
+}
+
+// Reset clears all memory, registers, timers, and the display, and reloads the font data
+func (m *Machine) Reset(mode InterpreterMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Instantiate memory, registers, timers, and counters
+	m.pc = uint16(MEM_ROM_START) // Program Counter
+	m.indexRegister = uint16(0)
+	m.stack = NewStack(DefaultQuirks(mode).StackDepth)
+	m.registers = make([]uint8, 16)
+	m.delayTimer = 0
+	m.soundTimer = 0
+
+	m.memory = make([]byte, memorySize(mode))
+	// Load fonts into memory, starting at MEM_FONT_DATA_START
+	for i, data := range fontData {
+		m.memory[MEM_FONT_DATA_START+i] = data
+	}
+	copy(m.memory[MEM_BIG_FONT_DATA_START:], bigFontData)
+
+	m.hires = false
+	m.planes = PLANE_1
+	m.clearDisplay()
+	m.audioPattern = [AUDIO_PATTERN_SIZE]byte{}
+	m.pitch = AUDIO_DEFAULT_PITCH
+	m.input = make([]bool, 16)
+	m.vblank = false
+	m.waitingOnFrame = false
+	m.keyAwaitingRelease = nil
+	m.halted = false
+	m.romHash = ""
+	m.romSize = 0
+	m.rom = nil
+	m.reseed()
+	m.runTarget = nil
+	m.skipBreakpointCheck = false
+	m.fault = nil
+	m.outOfRange = nil
+
+	m.mode = mode
+	m.quirks = DefaultQuirks(mode)
+}
+
+// Mode returns the mode the Machine was last reset into
+func (m *Machine) Mode() InterpreterMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mode
+}
+
+// supportsSuperChip reports whether the SUPER-CHIP instructions are available,
+// which XO-CHIP builds upon. The caller must hold m.mu
+func (m *Machine) supportsSuperChip() bool {
+	return m.mode == MODE_SUPERCHIP || m.mode == MODE_XOCHIP
+}
+
+// Halted reports whether the ROM has exited via 00FD
+func (m *Machine) Halted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.halted
+}
+
+// memorySize returns how many bytes of memory the mode has, 4K for CHIP-8 and SUPER-CHIP, and 64K for XO-CHIP
+func memorySize(mode InterpreterMode) int {
+	if mode == MODE_XOCHIP {
+		return MEM_SIZE_XOCHIP
+	}
+	return MEM_SIZE
+}
+
+// Errors for ROMs that can't be loaded. Errors returned while loading a ROM wrap one of these
+var (
+	ErrRomNotFound = errors.New("ROM not found")
+	ErrRomEmpty    = errors.New("ROM is empty")
+	ErrRomTooLarge = errors.New("ROM is too large")
+	ErrRomRead     = errors.New("failed to read ROM")
+)
+
+// LoadRom reads a whole ROM and copies it into memory, starting at MEM_ROM_START.
+// The ROM must fit in the mode's memory, which leaves 3584 bytes for CHIP-8 and SUPER-CHIP, and 65024 for XO-CHIP.
+// Memory is left untouched if the ROM can't be loaded
+func (m *Machine) LoadRom(romFile io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Read one byte more than fits in memory, to tell a ROM that exactly fits from one that doesn't
+	available := m.memory[MEM_ROM_START:]
+	rom, err := io.ReadAll(io.LimitReader(romFile, int64(len(available))+1))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRomRead, err)
+	}
+	romSize := len(rom)
+	if romSize == 0 {
+		return ErrRomEmpty
+	}
+	if romSize > len(available) {
+		// Count the rest of the ROM, so the error can say by how much it doesn't fit
+		rest, _ := io.Copy(io.Discard, romFile)
+		return fmt.Errorf("%w: %d bytes, but only %d fit in memory", ErrRomTooLarge, int64(romSize)+rest, len(available))
+	}
+	copy(available, rom)
+
+	romHash := sha1.Sum(m.memory[MEM_ROM_START : MEM_ROM_START+romSize])
+	m.romHash = hex.EncodeToString(romHash[:])
+	m.romSize = romSize
+	m.rom = rom
+	return nil
+}
+
+// LoadRomBytes copies a ROM that is already in memory, such as one built into the binary, see LoadRom
+func (m *Machine) LoadRomBytes(rom []byte) error {
+	return m.LoadRom(bytes.NewReader(rom))
+}
+
+// Restart resets the Machine and loads the same ROM again, keeping the current mode and quirks
+func (m *Machine) Restart() error {
+	m.mu.Lock()
+	mode, quirks, rom := m.mode, m.quirks, m.rom
+	m.mu.Unlock()
+
+	if rom == nil {
+		return errors.New("no ROM has been loaded to restart")
+	}
+	m.Reset(mode)
+	m.SetQuirks(quirks)
+	return m.LoadRomBytes(rom)
+}
+
+// RomHash returns the SHA-1 hash of the loaded ROM as a hex string, or "" if no ROM is loaded
+func (m *Machine) RomHash() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.romHash
+}
+
+// IsBuzzing reports whether the sound timer is active, and so the buzzer should be sounding
+func (m *Machine) IsBuzzing() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.soundTimer > 0
+}
+
+// AudioPattern returns the XO-CHIP audio pattern buffer and pitch register
+func (m *Machine) AudioPattern() ([AUDIO_PATTERN_SIZE]byte, uint8) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.audioPattern, m.pitch
+}
+
+// RunFrame runs a single 60Hz frame, ticking the timers once and then executing up to
+// instructions instructions. The frame ends early if a sprite draw has to wait for the next frame,
+// or if a breakpoint or debugger target is reached or an instruction faults, which is reported by the returned StopReason
+func (m *Machine) RunFrame(instructions int) StopReason {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A faulted machine stays stopped until the fault is dealt with
+	if m.fault != nil {
+		return STOP_FAULT
+	}
+	m.tickTimers()
+	m.vblank = true
+	m.waitingOnFrame = false
+	for range instructions {
+		if reason := m.checkStop(); reason != STOP_NONE {
+			return reason
+		}
+		m.step()
+		if m.fault != nil {
+			return STOP_FAULT
+		}
+		if m.waitingOnFrame || m.halted {
+			break
+		}
+	}
+	return STOP_NONE
+}
+
+// tickTimers decrements the delay and sound timers. The caller must hold m.mu
+func (m *Machine) tickTimers() {
+	if m.delayTimer > 0 {
+		m.delayTimer--
+	}
+	if m.soundTimer > 0 {
+		m.soundTimer--
+	}
+}
+
+// Step fetches, decodes, and executes a single instruction, outside of the frame schedule.
+// The timers are not ticked, and a sprite draw never waits for the display
+func (m *Machine) Step() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.debugStep()
+}
+
+// step executes a single instruction. The caller must hold m.mu
+func (m *Machine) step() {
+	if m.halted {
+		return
+	}
+
+	repeatOpcode := func() {
+		m.pc -= 2
+	}
+	skipNextOpcode := func() {
+		m.pc += m.instructionSize(m.pc)
+	}
+
+	m.fault = nil
+	// Running off the end of memory wraps back around to the start, or faults
+	if !m.checkMemory(Instruction{}, m.pc, int(m.pc), 2, false) {
+		return
+	}
+	m.pc = uint16(int(m.pc) % len(m.memory))
+	address := m.pc
+	ins := DecodeAt(m.memory, address)
+	if m.trace != nil {
+		fmt.Fprintf(m.trace, "%04X  %04X  %s\n", address, ins.Opcode, ins)
+	}
+	m.pc += 2
+	if !ins.Op.SupportedIn(m.mode) {
+		m.raiseFault(FAULT_UNKNOWN_OPCODE, ins, address, ErrUnknownOpcode)
+		return
+	}
+	// Anything going wrong while executing the instruction faults it, rather than taking down the whole app
+	defer func() {
+		if r := recover(); r != nil {
+			m.raiseFault(FAULT_PANIC, ins, address, fmt.Errorf("%w: %v", ErrPanic, r))
+		}
+	}()
+
+	x, y, n, nn, nnn := ins.X, ins.Y, ins.N, ins.NN, ins.NNN
+	registers := m.registers
+	switch ins.Op {
+	case OP_CLS:
+		// 00E0 - Clear Screen
+		m.clearPlanes()
+	case OP_RET:
+		// 00EE - Return from Subroutine
+		pc, err := m.stack.Pop()
+		if err != nil {
+			m.raiseFault(FAULT_STACK, ins, address, err)
+			return
+		}
+		m.pc = pc
+	case OP_SCD:
+		// 00CN - Scroll the display down N pixels
+		m.scroll(0, int(n))
+	case OP_SCU:
+		// 00DN - Scroll the display up N pixels
+		m.scroll(0, -int(n))
+	case OP_SCR:
+		// 00FB - Scroll the display right 4 pixels
+		m.scroll(4, 0)
+	case OP_SCL:
+		// 00FC - Scroll the display left 4 pixels
+		m.scroll(-4, 0)
+	case OP_EXIT:
+		// 00FD - Exit the interpreter
+		m.halted = true
+	case OP_LOW:
+		// 00FE - Switch to 64x32 lores mode
+		m.setHires(false)
+	case OP_HIGH:
+		// 00FF - Switch to 128x64 hires mode
+		m.setHires(true)
+	case OP_JP:
+		// 1NNN - Jump
+		m.pc = nnn
+	case OP_CALL:
+		// 2NNN -  Call subroutine at NNN
+		err := m.stack.Push(m.pc)
+		if err != nil {
+			m.raiseFault(FAULT_STACK, ins, address, err)
+			return
+		}
+		m.pc = nnn
+	case OP_SE_VX_NN:
+		// 3XNN - Skip if VX = NN
+		if registers[x] == nn {
+			skipNextOpcode()
+		}
+	case OP_SNE_VX_NN:
+		// 4XNN - Skip if VX != NN
+		if registers[x] != nn {
+			skipNextOpcode()
+		}
+	case OP_SE_VX_VY:
+		// 5XY0 - Skip if VX == VY
+		if registers[x] == registers[y] {
+			skipNextOpcode()
+		}
+	case OP_SAVE_RANGE:
+		// 5XY2 - Store VX to VY in memory, starting at address I
+		regs := registerRange(x, y)
+		if !m.checkMemory(ins, address, int(m.indexRegister), len(regs), true) {
+			return
+		}
+		for i, reg := range regs {
+			m.writeMemory(int(m.indexRegister)+i, registers[reg])
+		}
+	case OP_LOAD_RANGE:
+		// 5XY3 - Fetch VX to VY from memory, starting at address I
+		regs := registerRange(x, y)
+		if !m.checkMemory(ins, address, int(m.indexRegister), len(regs), false) {
+			return
+		}
+		for i, reg := range regs {
+			registers[reg] = m.readMemory(int(m.indexRegister) + i)
+		}
+	case OP_LD_VX_NN:
+		// 6XNN - Save NN to Register
+		registers[x] = nn
+	case OP_ADD_VX_NN:
+		// 7XNN - Add NN to VX
+		registers[x] += nn
+	case OP_LD_VX_VY:
+		// 8XY0 - Set VX to VY
+		registers[x] = registers[y]
+	case OP_OR:
+		// 8XY1 - Set VX to VX or VY (bitwise)
+		registers[x] = registers[x] | registers[y]
+		if m.quirks.VFReset {
+			registers[0x0F] = 0
+		}
+	case OP_AND:
+		// 8XY2 - Set VX to VX and VY (bitwise)
+		registers[x] = registers[x] & registers[y]
+		if m.quirks.VFReset {
+			registers[0x0F] = 0
+		}
+	case OP_XOR:
+		// 8XY3 - Set VX to VX xor VY
+		registers[x] = registers[x] ^ registers[y]
+		if m.quirks.VFReset {
+			registers[0x0F] = 0
+		}
+	case OP_ADD_VX_VY:
+		// 8XY4 - Add VY to VX (setting VF to 1 on overflow)
+		newVal := uint16(registers[x]) + uint16(registers[y])
+		var flag uint8 = 0
+		if newVal > 255 {
+			flag = 1
+		}
+		registers[x] = uint8(newVal)
+		registers[0xF] = flag
+	case OP_SUB:
+		// 8XY5 - Sub VY from VX (setting VF to 0 on underflow)
+		var flag uint8 = 0
+		if registers[x] >= registers[y] {
+			flag = 1
+		}
+		registers[x] -= registers[y]
+		registers[0xF] = flag
+	case OP_SHR:
+		// 8XY6 - Bitshift VX right 1, setting VF 1 to if LSB was shifted out
+		if !m.quirks.Shifting {
+			registers[x] = registers[y]
+		}
+		flag := registers[x] & 1
+		registers[x] = registers[x] >> 1
+		registers[0xF] = flag
+	case OP_SUBN:
+		// 8XY7 - Set VX to VY - VX (setting VF to 0 on underflow)
+		var flag uint8 = 0
+		if registers[y] >= registers[x] {
+			flag = 1
+		}
+		registers[x] = registers[y] - registers[x]
+		registers[0xF] = flag
+	case OP_SHL:
+		// 8XYE - Bitshift VX left 1, setting VF to 1 if MSB was shifted out
+		if !m.quirks.Shifting {
+			registers[x] = registers[y]
+		}
+		flag := registers[x] >> 7
+		registers[x] = registers[x] << 1
+		registers[0xF] = flag
+	case OP_SNE_VX_VY:
+		// 9XY0 - Skip if VX != VY
+		if registers[x] != registers[y] {
+			skipNextOpcode()
+		}
+	case OP_LD_I:
+		// ANNN - Save NNN to Index Register
+		m.indexRegister = nnn
+	case OP_JP_V0:
+		if m.quirks.Jumping {
+			// BXNN - Jump to address XNN plus VX
+			m.pc = nnn + uint16(registers[x])
+		} else {
+			// BNNN - Jump to address NNN plus V0
+			m.pc = nnn + uint16(registers[0])
+		}
+	case OP_RND:
+		// CXNN - Set VX to the NN & Rand
+		registers[x] = nn & m.randomByte()
+	case OP_DRW:
+		// DXYN - Draw to display
+		if m.quirks.DisplayWait {
+			// Sprites are only drawn once per frame, so wait for the next vertical blank
+			if !m.vblank {
+				repeatOpcode()
+				m.waitingOnFrame = true
+				return
+			}
+			m.vblank = false
+		}
+		if !m.checkMemory(ins, address, int(m.indexRegister), m.spriteSize(n), false) {
+			return
+		}
+		rowCount := m.drawSprite(registers[x], registers[y], n)
+		if m.mode == MODE_SUPERCHIP && m.hires {
+			// SUPER-CHIP reports the number of colliding rows in hires mode
+			registers[0x0F] = uint8(rowCount)
+		} else if rowCount > 0 {
+			registers[0x0F] = 1
+		} else {
+			registers[0x0F] = 0
+		}
+	case OP_SKP:
+		// EX9E - Skip if the key in VX is pressed. Only the low nibble of VX names a key
+		if m.input[registers[x]&0x0F] {
+			skipNextOpcode()
+		}
+	case OP_SKNP:
+		// EXA1 - Skip if the key in VX is not pressed
+		if !m.input[registers[x]&0x0F] {
+			skipNextOpcode()
+		}
+	case OP_LD_I_LONG:
+		// F000 NNNN - Save the 16-bit address NNNN to Index Register
+		if !m.checkMemory(ins, address, int(address)+2, 2, false) {
+			return
+		}
+		m.indexRegister = ins.Long
+		m.pc += 2
+	case OP_PLANE:
+		// FN01 - Select the bitplanes N to draw to
+		m.planes = x & PLANE_BOTH
+	case OP_AUDIO:
+		// F002 - Load 16 bytes starting at address I into the audio pattern buffer
+		if !m.checkMemory(ins, address, int(m.indexRegister), AUDIO_PATTERN_SIZE, false) {
+			return
+		}
+		for i := range m.audioPattern {
+			m.audioPattern[i] = m.readMemory(int(m.indexRegister) + i)
+		}
+	case OP_LD_VX_DT:
+		// FX07 - Set VX to the value of the delay timer
+		registers[x] = uint8(m.delayTimer)
+	case OP_LD_VX_K:
+		// FX0A - Await keypress
+		keypressDetected := false
+		if m.quirks.KeyRelease {
+			if m.keyAwaitingRelease != nil {
+				// Wait for the previously flagged 'pressed' key to be released
+				if !m.input[*m.keyAwaitingRelease] {
+					keypressDetected = true
+					registers[x] = uint8(*m.keyAwaitingRelease)
+					m.keyAwaitingRelease = nil
+				}
+			} else {
+				for i, key := range m.input {
+					if key {
+						// Flag the first pressed key
+						m.keyAwaitingRelease = &i
+						break
+					}
+				}
+			}
+		} else {
+			for i, key := range m.input {
+				if key {
+					// Take the first pressed key
+					keypressDetected = true
+					registers[x] = uint8(i)
+					break
+				}
+			}
+		}
+		if !keypressDetected {
+			repeatOpcode()
+		}
+	case OP_LD_DT_VX:
+		// FX15 - Set the delay timer to VX
+		m.delayTimer = registers[x]
+	case OP_LD_ST_VX:
+		// FX18 - Set the sound timer to VX
+		m.soundTimer = registers[x]
+	case OP_ADD_I_VX:
+		// FX1E - Add VX to I
+		m.indexRegister += uint16(registers[x])
+	case OP_LD_F_VX:
+		// FX29 - Set I to the location of the sprite for character VX
+		setChar := registers[x] & 0x0F
+		m.indexRegister = uint16(MEM_FONT_DATA_START) + uint16(setChar)*5
+	case OP_LD_HF_VX:
+		// FX30 - Set I to the location of the big sprite for character VX
+		setChar := registers[x] & 0x0F
+		m.indexRegister = uint16(MEM_BIG_FONT_DATA_START) + uint16(setChar)*10
+	case OP_PITCH:
+		// FX3A - Set the audio pitch register to VX
+		m.pitch = registers[x]
+	case OP_LD_B_VX:
+		// FX33 - Store a BCD representation of VX to memory location I
+		// Representation is i = hundreds, i+1 = tens, i+2 = ones
+		hundreds := registers[x] / 100
+		tens := (registers[x] - (100 * hundreds)) / 10
+		ones := registers[x] - (100 * hundreds) - (10 * tens)
+		if !m.checkMemory(ins, address, int(m.indexRegister), 3, true) {
+			return
+		}
+		m.writeMemory(int(m.indexRegister), hundreds)
+		m.writeMemory(int(m.indexRegister)+1, tens)
+		m.writeMemory(int(m.indexRegister)+2, ones)
+	case OP_LD_I_VX:
+		// FX55 - Stores V0 to VX in memory, starting at address I
+		if !m.checkMemory(ins, address, int(m.indexRegister), int(x)+1, true) {
+			return
+		}
+		for i := 0; i <= int(x); i++ {
+			m.writeMemory(int(m.indexRegister)+i, registers[i])
+		}
+		if m.quirks.MemoryIncrement {
+			m.indexRegister += uint16(x) + 1
+		}
+	case OP_LD_VX_I:
+		// FX65 - Fetches values for V0 to VX from memory, starting at address I
+		if !m.checkMemory(ins, address, int(m.indexRegister), int(x)+1, false) {
+			return
+		}
+		for i := 0; i <= int(x); i++ {
+			registers[i] = m.readMemory(int(m.indexRegister) + i)
+		}
+		if m.quirks.MemoryIncrement {
+			m.indexRegister += uint16(x) + 1
+		}
+	case OP_LD_R_VX:
+		// FX75 - Store V0 to VX in the RPL user flags
+		copy(m.rplFlags[:min(int(x)+1, m.rplFlagCount())], registers)
+	case OP_LD_VX_R:
+		// FX85 - Fetch V0 to VX from the RPL user flags
+		copy(registers, m.rplFlags[:min(int(x)+1, m.rplFlagCount())])
+	}
+}
+
+// rplFlagCount returns the number of RPL user flags available in the current mode.
+// The caller must hold m.mu
+func (m *Machine) rplFlagCount() int {
+	if m.mode == MODE_XOCHIP {
+		return RPL_FLAG_COUNT_XOCHIP
+	}
+	return RPL_FLAG_COUNT_SUPERCHIP
+}
+
+// registerRange lists the registers from VX to VY inclusive, counting down if X > Y
+func registerRange(x uint8, y uint8) []uint8 {
+	var regs []uint8
+	if x <= y {
+		for i := x; i <= y; i++ {
+			regs = append(regs, i)
+		}
+	} else {
+		for i := x; i >= y && i <= x; i-- {
+			regs = append(regs, i)
+		}
+	}
+	return regs
+}
+
+// instructionSize returns the length in bytes of the instruction at an address. F000 NNNN is twice the length
+// of other instructions, but only in XO-CHIP mode. The caller must hold m.mu
+func (m *Machine) instructionSize(address uint16) uint16 {
+	if m.mode == MODE_XOCHIP {
+		return DecodeAt(m.memory, address).Size()
+	}
+	return 2
+}
@@ -0,0 +1,102 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MAX_OUT_OF_RANGE_ACCESSES limits how many out of range accesses are recorded, one per instruction address
+const MAX_OUT_OF_RANGE_ACCESSES = 64
+
+var ErrMemoryOutOfRange = errors.New("memory access out of range")
+
+// OutOfRangeAccess is an instruction that read or wrote past the end of memory
+type OutOfRangeAccess struct {
+	PC      uint16
+	Opcode  uint16
+	Address int
+	Write   bool
+}
+
+func (access OutOfRangeAccess) String() string {
+	direction := "read from"
+	if access.Write {
+		direction = "write to"
+	}
+	return fmt.Sprintf("0x%03X (%04X): %s 0x%X", access.PC, access.Opcode, direction, access.Address)
+}
+
+// Memory returns a copy of the Machine's memory
+func (m *Machine) Memory() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]byte(nil), m.memory...)
+}
+
+// WriteMemory overwrites memory starting at an address, as long as all of the data fits
+func (m *Machine) WriteMemory(address uint16, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if int(address)+len(data) > len(m.memory) {
+		return fmt.Errorf("writing %d bytes at 0x%04X would overrun the end of memory at 0x%04X", len(data), address, len(m.memory))
+	}
+	copy(m.memory[address:], data)
+	return nil
+}
+
+// RomSize returns the size in bytes of the loaded ROM, which starts at MEM_ROM_START
+func (m *Machine) RomSize() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.romSize
+}
+
+// OutOfRangeAccesses lists the instructions that have accessed memory out of range since the last Reset,
+// in the order they were first seen
+func (m *Machine) OutOfRangeAccesses() []OutOfRangeAccess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]OutOfRangeAccess(nil), m.outOfRange...)
+}
+
+// checkMemory checks an instruction's access of length bytes starting at start. An access past the end of memory
+// is recorded, then either faults or is left to wrap around, depending on the MemoryFault quirk.
+// Returns false if the instruction faulted, and must stop. The caller must hold m.mu
+func (m *Machine) checkMemory(ins Instruction, pc uint16, start int, length int, write bool) bool {
+	if start+length <= len(m.memory) {
+		return true
+	}
+	address := max(start, len(m.memory))
+	m.recordOutOfRange(OutOfRangeAccess{PC: pc, Opcode: ins.Opcode, Address: address, Write: write})
+	if m.quirks.MemoryFault {
+		m.raiseFault(FAULT_MEMORY, ins, pc, fmt.Errorf("%w: 0x%X", ErrMemoryOutOfRange, address))
+		return false
+	}
+	return true
+}
+
+// recordOutOfRange adds an access to the record, unless its instruction is already there. The caller must hold m.mu
+func (m *Machine) recordOutOfRange(access OutOfRangeAccess) {
+	if len(m.outOfRange) >= MAX_OUT_OF_RANGE_ACCESSES {
+		return
+	}
+	for _, recorded := range m.outOfRange {
+		if recorded.PC == access.PC {
+			return
+		}
+	}
+	m.outOfRange = append(m.outOfRange, access)
+}
+
+// readMemory reads a byte, wrapping addresses past the end of memory back around to the start.
+// The caller must hold m.mu
+func (m *Machine) readMemory(address int) byte {
+	return m.memory[address%len(m.memory)]
+}
+
+// writeMemory writes a byte, wrapping addresses past the end of memory back around to the start.
+// The caller must hold m.mu
+func (m *Machine) writeMemory(address int, value byte) {
+	m.memory[address%len(m.memory)] = value
+}
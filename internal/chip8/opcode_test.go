@@ -0,0 +1,1178 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+var allModes = []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP, MODE_XOCHIP}
+
+var modeNames = map[InterpreterMode]string{
+	MODE_CHIP8:     "chip8",
+	MODE_SUPERCHIP: "superchip",
+	MODE_XOCHIP:    "xochip",
+}
+
+// machineState describes the parts of a Machine that an opcode test sets up beforehand, or checks afterwards.
+// Anything left unset is left at its reset value, or isn't checked
+type machineState struct {
+	V        map[int]uint8
+	I        *uint16
+	PC       *uint16
+	Stack    []uint16
+	DT       *uint8
+	ST       *uint8
+	Memory   map[uint16]uint8
+	Pixels   map[[2]int]uint8
+	Keys     []int
+	Hires    *bool
+	Planes   *uint8
+	Pitch    *uint8
+	RPLFlags map[int]uint8
+	Halted   *bool
+}
+
+type opcodeTest struct {
+	name string
+	// modes defaults to every mode
+	modes []InterpreterMode
+	// quirks adjusts the mode's default quirks
+	quirks func(*Quirks)
+	before machineState
+	// opcodes are loaded from 0x200 onwards
+	opcodes []uint16
+	// steps defaults to the number of opcodes
+	steps int
+	// noVblank runs the steps without the start of a new frame, so a sprite draw waiting on the display has to wait
+	noVblank bool
+	after    machineState
+	// check makes any checks that machineState can't describe
+	check func(t *testing.T, m *Machine)
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+var opcodeTests = []opcodeTest{
+	// 0NNN
+	{
+		name:    "00E0 clears the display",
+		before:  machineState{Pixels: map[[2]int]uint8{{0, 0}: 1, {63, 31}: 1}},
+		opcodes: []uint16{0x00E0},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: 0, {63, 31}: 0}, PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "00E0 only clears the selected plane",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{Planes: ptr[uint8](PLANE_1), Pixels: map[[2]int]uint8{{0, 0}: PLANE_BOTH}},
+		opcodes: []uint16{0x00E0},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: PLANE_2}},
+	},
+	{
+		name:    "00EE returns from a subroutine",
+		before:  machineState{Stack: []uint16{0x345}},
+		opcodes: []uint16{0x00EE},
+		after:   machineState{PC: ptr[uint16](0x345), Stack: []uint16{}},
+	},
+	{
+		name:    "00EE faults on an empty stack",
+		opcodes: []uint16{0x00EE},
+		after:   machineState{PC: ptr[uint16](0x200), Stack: []uint16{}},
+		check:   checkFault(ErrStackUnderflow),
+	},
+	{
+		name:    "00CN scrolls down",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00C3},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 0, {5, 8}: 1}},
+	},
+	{
+		name:    "00CN is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00C3},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 1, {5, 8}: 0}, PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "00DN scrolls up",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00D2},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 0, {5, 3}: 1}},
+	},
+	{
+		name:    "00DN is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00D2},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 1, {5, 3}: 0}},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "00FB scrolls right 4 pixels",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00FB},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 0, {9, 5}: 1}},
+	},
+	{
+		name:    "00FC scrolls left 4 pixels",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Pixels: map[[2]int]uint8{{5, 5}: 1}},
+		opcodes: []uint16{0x00FC},
+		after:   machineState{Pixels: map[[2]int]uint8{{5, 5}: 0, {1, 5}: 1}},
+	},
+	{
+		name:    "00FD halts the interpreter",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		opcodes: []uint16{0x00FD, 0x6001},
+		after:   machineState{Halted: ptr(true), PC: ptr[uint16](0x202), V: map[int]uint8{0: 0}},
+	},
+	{
+		name:    "00FD is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		opcodes: []uint16{0x00FD},
+		after:   machineState{Halted: ptr(false), PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "00FF switches to hires",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		opcodes: []uint16{0x00FF},
+		after:   machineState{Hires: ptr(true)},
+	},
+	{
+		name:    "00FE switches back to lores",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Hires: ptr(true)},
+		opcodes: []uint16{0x00FE},
+		after:   machineState{Hires: ptr(false)},
+	},
+	{
+		name:    "00FF is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		opcodes: []uint16{0x00FF},
+		after:   machineState{Hires: ptr(false), PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+
+	// 1NNN and 2NNN
+	{
+		name:    "1NNN jumps",
+		opcodes: []uint16{0x1345},
+		after:   machineState{PC: ptr[uint16](0x345)},
+	},
+	{
+		name:    "2NNN calls a subroutine",
+		opcodes: []uint16{0x2345},
+		after:   machineState{PC: ptr[uint16](0x345), Stack: []uint16{0x202}},
+	},
+	{
+		name:    "2NNN faults when the COSMAC stack is full",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		before:  machineState{Stack: make([]uint16, STACK_DEPTH_COSMAC)},
+		opcodes: []uint16{0x2345},
+		after:   machineState{PC: ptr[uint16](0x200), Stack: make([]uint16, STACK_DEPTH_COSMAC)},
+		check:   checkFault(ErrStackOverflow),
+	},
+	{
+		name:    "2NNN calls with 12 levels already on the stack",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Stack: make([]uint16, STACK_DEPTH_COSMAC)},
+		opcodes: []uint16{0x2345},
+		after:   machineState{PC: ptr[uint16](0x345), Stack: append(make([]uint16, STACK_DEPTH_COSMAC), 0x202)},
+	},
+	{
+		name:    "2NNN faults when the SUPER-CHIP stack is full",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{Stack: make([]uint16, STACK_DEPTH_SUPERCHIP)},
+		opcodes: []uint16{0x2345},
+		after:   machineState{PC: ptr[uint16](0x200), Stack: make([]uint16, STACK_DEPTH_SUPERCHIP)},
+		check:   checkFault(ErrStackOverflow),
+	},
+
+	// Skips
+	{
+		name:    "3XNN skips when equal",
+		before:  machineState{V: map[int]uint8{1: 0x42}},
+		opcodes: []uint16{0x3142},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "3XNN doesn't skip when not equal",
+		before:  machineState{V: map[int]uint8{1: 0x41}},
+		opcodes: []uint16{0x3142},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "4XNN skips when not equal",
+		before:  machineState{V: map[int]uint8{1: 0x41}},
+		opcodes: []uint16{0x4142},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "4XNN doesn't skip when equal",
+		before:  machineState{V: map[int]uint8{1: 0x42}},
+		opcodes: []uint16{0x4142},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "5XY0 skips when equal",
+		before:  machineState{V: map[int]uint8{1: 7, 2: 7}},
+		opcodes: []uint16{0x5120},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "5XY0 doesn't skip when not equal",
+		before:  machineState{V: map[int]uint8{1: 7, 2: 8}},
+		opcodes: []uint16{0x5120},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "9XY0 skips when not equal",
+		before:  machineState{V: map[int]uint8{1: 7, 2: 8}},
+		opcodes: []uint16{0x9120},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "9XY0 doesn't skip when equal",
+		before:  machineState{V: map[int]uint8{1: 7, 2: 7}},
+		opcodes: []uint16{0x9120},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "skips over the whole of F000 NNNN",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{1: 0x42}},
+		opcodes: []uint16{0x3142, 0xF000, 0x1234},
+		steps:   1,
+		after:   machineState{PC: ptr[uint16](0x206)},
+	},
+	{
+		name:    "skips F000 as a single instruction",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		before:  machineState{V: map[int]uint8{1: 0x42}},
+		opcodes: []uint16{0x3142, 0xF000, 0x1234},
+		steps:   1,
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+
+	// 5XY2 and 5XY3
+	{
+		name:    "5XY2 saves a range of registers",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{1: 1, 2: 2, 3: 3}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0x5132},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 2, 0x302: 3, 0x303: 0}, I: ptr[uint16](0x300)},
+	},
+	{
+		name:    "5XY2 saves a range of registers in reverse",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{1: 1, 2: 2, 3: 3}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0x5312},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 3, 0x301: 2, 0x302: 1}},
+	},
+	{
+		name:    "5XY3 loads a range of registers",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{Memory: map[uint16]uint8{0x300: 9, 0x301: 8}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0x5123},
+		after:   machineState{V: map[int]uint8{1: 9, 2: 8}, I: ptr[uint16](0x300)},
+	},
+	{
+		name:    "5XY2 is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		before:  machineState{V: map[int]uint8{1: 1, 2: 2}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0x5122},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 0}, PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+
+	// 6XNN and 7XNN
+	{
+		name:    "6XNN sets a register",
+		opcodes: []uint16{0x6A5C},
+		after:   machineState{V: map[int]uint8{0xA: 0x5C}},
+	},
+	{
+		name:    "7XNN adds without setting the carry flag",
+		before:  machineState{V: map[int]uint8{1: 0xFF, 0xF: 5}},
+		opcodes: []uint16{0x7102},
+		after:   machineState{V: map[int]uint8{1: 0x01, 0xF: 5}},
+	},
+
+	// 8XYN
+	{
+		name:    "8XY0 copies a register",
+		before:  machineState{V: map[int]uint8{2: 7}},
+		opcodes: []uint16{0x8120},
+		after:   machineState{V: map[int]uint8{1: 7, 2: 7}},
+	},
+	{
+		name:    "8XY1 ORs, resetting VF",
+		quirks:  func(q *Quirks) { q.VFReset = true },
+		before:  machineState{V: map[int]uint8{1: 0x0F, 2: 0xF0, 0xF: 9}},
+		opcodes: []uint16{0x8121},
+		after:   machineState{V: map[int]uint8{1: 0xFF, 0xF: 0}},
+	},
+	{
+		name:    "8XY1 ORs, leaving VF",
+		quirks:  func(q *Quirks) { q.VFReset = false },
+		before:  machineState{V: map[int]uint8{1: 0x0F, 2: 0xF0, 0xF: 9}},
+		opcodes: []uint16{0x8121},
+		after:   machineState{V: map[int]uint8{1: 0xFF, 0xF: 9}},
+	},
+	{
+		name:    "8XY2 ANDs, resetting VF",
+		quirks:  func(q *Quirks) { q.VFReset = true },
+		before:  machineState{V: map[int]uint8{1: 0x3C, 2: 0x0F, 0xF: 9}},
+		opcodes: []uint16{0x8122},
+		after:   machineState{V: map[int]uint8{1: 0x0C, 0xF: 0}},
+	},
+	{
+		name:    "8XY2 ANDs, leaving VF",
+		quirks:  func(q *Quirks) { q.VFReset = false },
+		before:  machineState{V: map[int]uint8{1: 0x3C, 2: 0x0F, 0xF: 9}},
+		opcodes: []uint16{0x8122},
+		after:   machineState{V: map[int]uint8{1: 0x0C, 0xF: 9}},
+	},
+	{
+		name:    "8XY3 XORs, resetting VF",
+		quirks:  func(q *Quirks) { q.VFReset = true },
+		before:  machineState{V: map[int]uint8{1: 0x3C, 2: 0x0F, 0xF: 9}},
+		opcodes: []uint16{0x8123},
+		after:   machineState{V: map[int]uint8{1: 0x33, 0xF: 0}},
+	},
+	{
+		name:    "8XY3 XORs, leaving VF",
+		quirks:  func(q *Quirks) { q.VFReset = false },
+		before:  machineState{V: map[int]uint8{1: 0x3C, 2: 0x0F, 0xF: 9}},
+		opcodes: []uint16{0x8123},
+		after:   machineState{V: map[int]uint8{1: 0x33, 0xF: 9}},
+	},
+	{
+		name:    "8XY4 adds with a carry",
+		before:  machineState{V: map[int]uint8{1: 0xF0, 2: 0x20}},
+		opcodes: []uint16{0x8124},
+		after:   machineState{V: map[int]uint8{1: 0x10, 0xF: 1}},
+	},
+	{
+		name:    "8XY4 adds without a carry",
+		before:  machineState{V: map[int]uint8{1: 0x10, 2: 0x20, 0xF: 1}},
+		opcodes: []uint16{0x8124},
+		after:   machineState{V: map[int]uint8{1: 0x30, 0xF: 0}},
+	},
+	{
+		name:    "8XY4 sets the flag after the result when X is F",
+		before:  machineState{V: map[int]uint8{1: 0x20, 0xF: 0xF0}},
+		opcodes: []uint16{0x8F14},
+		after:   machineState{V: map[int]uint8{0xF: 1}},
+	},
+	{
+		name:    "8XY4 reads VF before setting the flag when Y is F",
+		before:  machineState{V: map[int]uint8{1: 0x01, 0xF: 0xFF}},
+		opcodes: []uint16{0x81F4},
+		after:   machineState{V: map[int]uint8{1: 0x00, 0xF: 1}},
+	},
+	{
+		name:    "8XY5 subtracts without a borrow",
+		before:  machineState{V: map[int]uint8{1: 0x30, 2: 0x10}},
+		opcodes: []uint16{0x8125},
+		after:   machineState{V: map[int]uint8{1: 0x20, 0xF: 1}},
+	},
+	{
+		name:    "8XY5 subtracts with a borrow",
+		before:  machineState{V: map[int]uint8{1: 0x10, 2: 0x30, 0xF: 1}},
+		opcodes: []uint16{0x8125},
+		after:   machineState{V: map[int]uint8{1: 0xE0, 0xF: 0}},
+	},
+	{
+		name:    "8XY5 doesn't borrow from an equal value",
+		before:  machineState{V: map[int]uint8{1: 0x10, 2: 0x10}},
+		opcodes: []uint16{0x8125},
+		after:   machineState{V: map[int]uint8{1: 0x00, 0xF: 1}},
+	},
+	{
+		name:    "8XY5 sets the flag after the result when X is F",
+		before:  machineState{V: map[int]uint8{1: 0x30, 0xF: 0x10}},
+		opcodes: []uint16{0x8F15},
+		after:   machineState{V: map[int]uint8{0xF: 0}},
+	},
+	{
+		name:    "8XY6 shifts VY right into VX",
+		quirks:  func(q *Quirks) { q.Shifting = false },
+		before:  machineState{V: map[int]uint8{1: 0xFF, 2: 0x05}},
+		opcodes: []uint16{0x8126},
+		after:   machineState{V: map[int]uint8{1: 0x02, 2: 0x05, 0xF: 1}},
+	},
+	{
+		name:    "8XY6 shifts VX right in place",
+		quirks:  func(q *Quirks) { q.Shifting = true },
+		before:  machineState{V: map[int]uint8{1: 0xFE, 2: 0x05}},
+		opcodes: []uint16{0x8126},
+		after:   machineState{V: map[int]uint8{1: 0x7F, 2: 0x05, 0xF: 0}},
+	},
+	{
+		name:    "8XY6 sets the flag after the result when X is F",
+		quirks:  func(q *Quirks) { q.Shifting = false },
+		before:  machineState{V: map[int]uint8{0: 0x04}},
+		opcodes: []uint16{0x8F06},
+		after:   machineState{V: map[int]uint8{0xF: 0}},
+	},
+	{
+		name:    "8XY7 subtracts VX from VY without a borrow",
+		before:  machineState{V: map[int]uint8{1: 0x10, 2: 0x30}},
+		opcodes: []uint16{0x8127},
+		after:   machineState{V: map[int]uint8{1: 0x20, 0xF: 1}},
+	},
+	{
+		name:    "8XY7 subtracts VX from VY with a borrow",
+		before:  machineState{V: map[int]uint8{1: 0x30, 2: 0x10, 0xF: 1}},
+		opcodes: []uint16{0x8127},
+		after:   machineState{V: map[int]uint8{1: 0xE0, 0xF: 0}},
+	},
+	{
+		name:    "8XYE shifts VY left into VX",
+		quirks:  func(q *Quirks) { q.Shifting = false },
+		before:  machineState{V: map[int]uint8{1: 0x00, 2: 0x81}},
+		opcodes: []uint16{0x812E},
+		after:   machineState{V: map[int]uint8{1: 0x02, 2: 0x81, 0xF: 1}},
+	},
+	{
+		name:    "8XYE shifts VX left in place",
+		quirks:  func(q *Quirks) { q.Shifting = true },
+		before:  machineState{V: map[int]uint8{1: 0x40, 2: 0x81, 0xF: 1}},
+		opcodes: []uint16{0x812E},
+		after:   machineState{V: map[int]uint8{1: 0x80, 2: 0x81, 0xF: 0}},
+	},
+
+	// ANNN, BNNN, and CXNN
+	{
+		name:    "ANNN sets I",
+		opcodes: []uint16{0xA123},
+		after:   machineState{I: ptr[uint16](0x123)},
+	},
+	{
+		name:    "BNNN jumps offset by V0",
+		quirks:  func(q *Quirks) { q.Jumping = false },
+		before:  machineState{V: map[int]uint8{0: 0x10, 2: 0x30}},
+		opcodes: []uint16{0xB210},
+		after:   machineState{PC: ptr[uint16](0x220)},
+	},
+	{
+		name:    "BXNN jumps offset by VX",
+		quirks:  func(q *Quirks) { q.Jumping = true },
+		before:  machineState{V: map[int]uint8{0: 0x10, 2: 0x30}},
+		opcodes: []uint16{0xB210},
+		after:   machineState{PC: ptr[uint16](0x240)},
+	},
+	{
+		name:    "CXNN masks with NN",
+		before:  machineState{V: map[int]uint8{1: 0xFF}},
+		opcodes: []uint16{0xC100, 0xC20F},
+		after:   machineState{V: map[int]uint8{1: 0}},
+		check: func(t *testing.T, m *Machine) {
+			if m.registers[2]&0xF0 != 0 {
+				t.Errorf("V2 = %02X, which isn't masked by 0F", m.registers[2])
+			}
+		},
+	},
+
+	// DXYN
+	{
+		name:    "DXYN draws a sprite",
+		before:  machineState{V: map[int]uint8{1: 2, 2: 3, 0xF: 1}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0xC0}},
+		opcodes: []uint16{0xD121},
+		after:   machineState{Pixels: map[[2]int]uint8{{2, 3}: 1, {3, 3}: 1, {4, 3}: 0}, V: map[int]uint8{0xF: 0}},
+	},
+	{
+		name: "DXYN reports a collision",
+		before: machineState{
+			V: map[int]uint8{1: 2, 2: 3}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0xC0},
+			Pixels: map[[2]int]uint8{{2, 3}: 1},
+		},
+		opcodes: []uint16{0xD121},
+		after:   machineState{Pixels: map[[2]int]uint8{{2, 3}: 0, {3, 3}: 1}, V: map[int]uint8{0xF: 1}},
+	},
+	{
+		name:    "DXYN wraps the starting position",
+		before:  machineState{V: map[int]uint8{1: 66, 2: 35}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80}},
+		opcodes: []uint16{0xD121},
+		after:   machineState{Pixels: map[[2]int]uint8{{2, 3}: 1}},
+	},
+	{
+		name:    "DXYN clips at the right edge",
+		quirks:  func(q *Quirks) { q.Clipping = true },
+		before:  machineState{V: map[int]uint8{1: 63}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0xC0}},
+		opcodes: []uint16{0xD101},
+		after:   machineState{Pixels: map[[2]int]uint8{{63, 0}: 1, {0, 0}: 0}},
+	},
+	{
+		name:    "DXYN wraps at the right edge",
+		quirks:  func(q *Quirks) { q.Clipping = false },
+		before:  machineState{V: map[int]uint8{1: 63}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0xC0}},
+		opcodes: []uint16{0xD101},
+		after:   machineState{Pixels: map[[2]int]uint8{{63, 0}: 1, {0, 0}: 1}},
+	},
+	{
+		name:    "DXYN clips at the bottom edge",
+		quirks:  func(q *Quirks) { q.Clipping = true },
+		before:  machineState{V: map[int]uint8{2: 31}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x80}},
+		opcodes: []uint16{0xD022},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 31}: 1, {0, 0}: 0}},
+	},
+	{
+		name:    "DXYN wraps at the bottom edge",
+		quirks:  func(q *Quirks) { q.Clipping = false },
+		before:  machineState{V: map[int]uint8{2: 31}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x80}},
+		opcodes: []uint16{0xD022},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 31}: 1, {0, 0}: 1}},
+	},
+	{
+		name:     "DXYN waits for the display",
+		quirks:   func(q *Quirks) { q.DisplayWait = true },
+		before:   machineState{I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80}},
+		opcodes:  []uint16{0xD001},
+		noVblank: true,
+		after:    machineState{PC: ptr[uint16](0x200), Pixels: map[[2]int]uint8{{0, 0}: 0}},
+	},
+	{
+		name:     "DXYN draws without waiting for the display",
+		quirks:   func(q *Quirks) { q.DisplayWait = false },
+		before:   machineState{I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80}},
+		opcodes:  []uint16{0xD001},
+		noVblank: true,
+		after:    machineState{PC: ptr[uint16](0x202), Pixels: map[[2]int]uint8{{0, 0}: 1}},
+	},
+	{
+		name:    "DXY0 draws a 16x16 sprite",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x01, 0x31E: 0x80}},
+		opcodes: []uint16{0xD000},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: 1, {15, 0}: 1, {0, 15}: 1}},
+	},
+	{
+		name:    "DXY0 draws nothing",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		before:  machineState{I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80}},
+		opcodes: []uint16{0xD000},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: 0}},
+	},
+	{
+		name:  "DXYN counts colliding rows in hires",
+		modes: []InterpreterMode{MODE_SUPERCHIP},
+		before: machineState{
+			Hires: ptr(true), I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x80},
+			Pixels: map[[2]int]uint8{{0, 0}: 1, {0, 1}: 1},
+		},
+		opcodes: []uint16{0xD002},
+		after:   machineState{V: map[int]uint8{0xF: 2}},
+	},
+	{
+		name:    "DXYN counts rows clipped off the bottom in hires",
+		modes:   []InterpreterMode{MODE_SUPERCHIP},
+		before:  machineState{Hires: ptr(true), V: map[int]uint8{2: 63}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x80, 0x302: 0x80}},
+		opcodes: []uint16{0xD023},
+		after:   machineState{V: map[int]uint8{0xF: 2}},
+	},
+	{
+		name:  "DXYN only flags a collision in hires",
+		modes: []InterpreterMode{MODE_XOCHIP},
+		before: machineState{
+			Hires: ptr(true), I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x80},
+			Pixels: map[[2]int]uint8{{0, 0}: 1, {0, 1}: 1},
+		},
+		opcodes: []uint16{0xD002},
+		after:   machineState{V: map[int]uint8{0xF: 1}},
+	},
+	{
+		name:    "DXYN draws to both planes",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{Planes: ptr[uint8](PLANE_BOTH), I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0x80, 0x301: 0x40}},
+		opcodes: []uint16{0xD001},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: PLANE_1, {1, 0}: PLANE_2}},
+	},
+
+	// EXNN
+	{
+		name:    "EX9E skips when the key is pressed",
+		before:  machineState{V: map[int]uint8{1: 5}, Keys: []int{5}},
+		opcodes: []uint16{0xE19E},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "EX9E doesn't skip when the key isn't pressed",
+		before:  machineState{V: map[int]uint8{1: 5}, Keys: []int{4}},
+		opcodes: []uint16{0xE19E},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "EX9E only uses the low nibble of VX",
+		before:  machineState{V: map[int]uint8{1: 0x25}, Keys: []int{5}},
+		opcodes: []uint16{0xE19E},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "EXA1 skips when the key isn't pressed",
+		before:  machineState{V: map[int]uint8{1: 5}, Keys: []int{4}},
+		opcodes: []uint16{0xE1A1},
+		after:   machineState{PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "EXA1 doesn't skip when the key is pressed",
+		before:  machineState{V: map[int]uint8{1: 5}, Keys: []int{5}},
+		opcodes: []uint16{0xE1A1},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "EXA1 only uses the low nibble of VX",
+		before:  machineState{V: map[int]uint8{1: 0xF5}, Keys: []int{5}},
+		opcodes: []uint16{0xE1A1},
+		after:   machineState{PC: ptr[uint16](0x202)},
+	},
+
+	// FXNN
+	{
+		name:    "F000 NNNN sets I to a 16-bit address",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		opcodes: []uint16{0xF000, 0x1234},
+		steps:   1,
+		after:   machineState{I: ptr[uint16](0x1234), PC: ptr[uint16](0x204)},
+	},
+	{
+		name:    "F000 is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		opcodes: []uint16{0xF000, 0x6101},
+		steps:   1,
+		after:   machineState{I: ptr[uint16](0), PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "FN01 selects planes",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		opcodes: []uint16{0xF201},
+		after:   machineState{Planes: ptr[uint8](PLANE_2)},
+	},
+	{
+		name:    "F002 loads the audio pattern",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 0xAA, 0x30F: 0x55}},
+		opcodes: []uint16{0xF002},
+		check: func(t *testing.T, m *Machine) {
+			if m.audioPattern[0] != 0xAA || m.audioPattern[15] != 0x55 {
+				t.Errorf("audio pattern = %X, expected it to be loaded from 0x300", m.audioPattern)
+			}
+		},
+	},
+	{
+		name:    "FX07 reads the delay timer",
+		before:  machineState{DT: ptr[uint8](0x33)},
+		opcodes: []uint16{0xF107},
+		after:   machineState{V: map[int]uint8{1: 0x33}},
+	},
+	{
+		name:    "FX0A waits for a key",
+		opcodes: []uint16{0xF10A},
+		after:   machineState{PC: ptr[uint16](0x200)},
+	},
+	{
+		name:    "FX0A takes a pressed key",
+		quirks:  func(q *Quirks) { q.KeyRelease = false },
+		before:  machineState{Keys: []int{7}},
+		opcodes: []uint16{0xF10A},
+		after:   machineState{V: map[int]uint8{1: 7}, PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "FX0A takes the first of several pressed keys",
+		quirks:  func(q *Quirks) { q.KeyRelease = false },
+		before:  machineState{Keys: []int{3, 7, 0xC}},
+		opcodes: []uint16{0xF10A},
+		after:   machineState{V: map[int]uint8{1: 3}, PC: ptr[uint16](0x202)},
+	},
+	{
+		name:    "FX0A waits for the first of several pressed keys to be released",
+		quirks:  func(q *Quirks) { q.KeyRelease = true },
+		before:  machineState{Keys: []int{3, 7}},
+		opcodes: []uint16{0xF10A},
+		check: func(t *testing.T, m *Machine) {
+			if m.keyAwaitingRelease == nil || *m.keyAwaitingRelease != 3 {
+				t.Errorf("awaiting release of %v, expected key 3", m.keyAwaitingRelease)
+			}
+		},
+	},
+	{
+		name:    "FX0A waits for a pressed key to be released",
+		quirks:  func(q *Quirks) { q.KeyRelease = true },
+		before:  machineState{Keys: []int{7}},
+		opcodes: []uint16{0xF10A},
+		after:   machineState{V: map[int]uint8{1: 0}, PC: ptr[uint16](0x200)},
+	},
+	{
+		name:    "FX15 sets the delay timer",
+		before:  machineState{V: map[int]uint8{1: 0x20}},
+		opcodes: []uint16{0xF115},
+		after:   machineState{DT: ptr[uint8](0x20)},
+	},
+	{
+		name:    "FX18 sets the sound timer",
+		before:  machineState{V: map[int]uint8{1: 0x20}},
+		opcodes: []uint16{0xF118},
+		after:   machineState{ST: ptr[uint8](0x20)},
+	},
+	{
+		name:    "FX1E adds to I",
+		before:  machineState{V: map[int]uint8{1: 0x05}, I: ptr[uint16](0x10)},
+		opcodes: []uint16{0xF11E},
+		after:   machineState{I: ptr[uint16](0x15)},
+	},
+	{
+		name:    "FX29 points I at a font character",
+		before:  machineState{V: map[int]uint8{1: 0x0A}},
+		opcodes: []uint16{0xF129},
+		after:   machineState{I: ptr[uint16](MEM_FONT_DATA_START + 0xA*5)},
+	},
+	{
+		name:    "FX29 only uses the low nibble of VX",
+		before:  machineState{V: map[int]uint8{1: 0x4A}},
+		opcodes: []uint16{0xF129},
+		after:   machineState{I: ptr[uint16](MEM_FONT_DATA_START + 0xA*5)},
+	},
+	{
+		name:    "FX30 points I at a big font character",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{1: 0x0A}},
+		opcodes: []uint16{0xF130},
+		after:   machineState{I: ptr[uint16](MEM_BIG_FONT_DATA_START + 0xA*10)},
+	},
+	{
+		name:    "FX30 is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		before:  machineState{V: map[int]uint8{1: 0x0A}},
+		opcodes: []uint16{0xF130},
+		after:   machineState{I: ptr[uint16](0)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "FX33 stores BCD",
+		before:  machineState{V: map[int]uint8{1: 254}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 2, 0x301: 5, 0x302: 4}, I: ptr[uint16](0x300)},
+	},
+	{
+		name:    "FX33 stores BCD with leading zeroes",
+		before:  machineState{V: map[int]uint8{1: 7}, I: ptr[uint16](0x300), Memory: map[uint16]uint8{0x300: 9, 0x301: 9}},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 0, 0x301: 0, 0x302: 7}},
+	},
+	{
+		name:    "FX33 stores BCD with trailing zeroes",
+		before:  machineState{V: map[int]uint8{1: 100}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 0, 0x302: 0}},
+	},
+	{
+		name:    "FX3A sets the pitch",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{1: 0x70}},
+		opcodes: []uint16{0xF13A},
+		after:   machineState{Pitch: ptr[uint8](0x70)},
+	},
+	{
+		name:    "FX55 stores registers, incrementing I",
+		quirks:  func(q *Quirks) { q.MemoryIncrement = true },
+		before:  machineState{V: map[int]uint8{0: 1, 1: 2, 2: 3, 3: 4}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF255},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 2, 0x302: 3, 0x303: 0}, I: ptr[uint16](0x303)},
+	},
+	{
+		name:    "FX55 stores registers, leaving I",
+		quirks:  func(q *Quirks) { q.MemoryIncrement = false },
+		before:  machineState{V: map[int]uint8{0: 1, 1: 2, 2: 3}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF255},
+		after:   machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 2, 0x302: 3}, I: ptr[uint16](0x300)},
+	},
+	{
+		name:    "FX65 loads registers, incrementing I",
+		quirks:  func(q *Quirks) { q.MemoryIncrement = true },
+		before:  machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 2, 0x302: 3}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF165},
+		after:   machineState{V: map[int]uint8{0: 1, 1: 2, 2: 0}, I: ptr[uint16](0x302)},
+	},
+	{
+		name:    "FX65 loads registers, leaving I",
+		quirks:  func(q *Quirks) { q.MemoryIncrement = false },
+		before:  machineState{Memory: map[uint16]uint8{0x300: 1, 0x301: 2, 0x302: 3}, I: ptr[uint16](0x300)},
+		opcodes: []uint16{0xF165},
+		after:   machineState{V: map[int]uint8{0: 1, 1: 2, 2: 0}, I: ptr[uint16](0x300)},
+	},
+	{
+		name:    "FX75 saves the RPL flags",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{0: 1, 1: 2, 2: 3}},
+		opcodes: []uint16{0xF175},
+		after:   machineState{RPLFlags: map[int]uint8{0: 1, 1: 2, 2: 0}},
+	},
+	{
+		name:    "FX75 only has 8 RPL flags",
+		modes:   []InterpreterMode{MODE_SUPERCHIP},
+		before:  machineState{V: map[int]uint8{7: 7, 8: 8}},
+		opcodes: []uint16{0xF875},
+		after:   machineState{RPLFlags: map[int]uint8{7: 7, 8: 0}},
+	},
+	{
+		name:    "FX75 has 16 RPL flags",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		before:  machineState{V: map[int]uint8{7: 7, 8: 8, 0xF: 0xF}},
+		opcodes: []uint16{0xFF75},
+		after:   machineState{RPLFlags: map[int]uint8{7: 7, 8: 8, 0xF: 0xF}},
+	},
+	{
+		name:    "FX85 loads the RPL flags",
+		modes:   []InterpreterMode{MODE_SUPERCHIP, MODE_XOCHIP},
+		before:  machineState{RPLFlags: map[int]uint8{0: 1, 1: 2, 2: 3}},
+		opcodes: []uint16{0xF185},
+		after:   machineState{V: map[int]uint8{0: 1, 1: 2, 2: 0}},
+	},
+	{
+		name:    "FX75 is unsupported",
+		modes:   []InterpreterMode{MODE_CHIP8},
+		before:  machineState{V: map[int]uint8{0: 1}},
+		opcodes: []uint16{0xF075},
+		after:   machineState{RPLFlags: map[int]uint8{0: 0}},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+
+	// Out of range memory
+	{
+		name:    "FX33 wraps past the end of memory",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = false },
+		before:  machineState{V: map[int]uint8{1: 254}, I: ptr[uint16](0xFFE)},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0xFFE: 2, 0xFFF: 5, 0x000: 4}, PC: ptr[uint16](0x202)},
+		check:   checkOutOfRange(OutOfRangeAccess{PC: 0x200, Opcode: 0xF133, Address: 0x1000, Write: true}),
+	},
+	{
+		name:    "FX33 wraps past the end of XO-CHIP memory",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = false },
+		before:  machineState{V: map[int]uint8{1: 254}, I: ptr[uint16](0xFFFE)},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0xFFFE: 2, 0xFFFF: 5, 0x0000: 4}},
+		check:   checkOutOfRange(OutOfRangeAccess{PC: 0x200, Opcode: 0xF133, Address: 0x10000, Write: true}),
+	},
+	{
+		name:    "FX33 faults past the end of memory",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = true },
+		before:  machineState{V: map[int]uint8{1: 254}, I: ptr[uint16](0xFFE)},
+		opcodes: []uint16{0xF133},
+		after:   machineState{Memory: map[uint16]uint8{0xFFE: 0, 0xFFF: 0, 0x000: 0}, PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:    "FX65 wraps past the end of memory",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = false; q.MemoryIncrement = false },
+		before:  machineState{I: ptr[uint16](0xFFF), Memory: map[uint16]uint8{0xFFF: 7, 0x000: 8}},
+		opcodes: []uint16{0xF165},
+		after:   machineState{V: map[int]uint8{0: 7, 1: 8}},
+		check:   checkOutOfRange(OutOfRangeAccess{PC: 0x200, Opcode: 0xF165, Address: 0x1000}),
+	},
+	{
+		name:    "FX55 faults past the end of memory",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = true },
+		before:  machineState{V: map[int]uint8{0: 7, 1: 8}, I: ptr[uint16](0xFFF)},
+		opcodes: []uint16{0xF155},
+		after:   machineState{Memory: map[uint16]uint8{0xFFF: 0}, I: ptr[uint16](0xFFF)},
+		check:   checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:    "DXYN faults reading a sprite past the end of memory",
+		modes:   []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = true },
+		before:  machineState{I: ptr[uint16](0xFFE), Memory: map[uint16]uint8{0xFFE: 0x80}},
+		opcodes: []uint16{0xD003},
+		after:   machineState{Pixels: map[[2]int]uint8{{0, 0}: 0}},
+		check:   checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:    "DXYN reads a sprite for each plane",
+		modes:   []InterpreterMode{MODE_XOCHIP},
+		quirks:  func(q *Quirks) { q.MemoryFault = true },
+		before:  machineState{Planes: ptr[uint8](PLANE_BOTH), I: ptr[uint16](0xFFFE)},
+		opcodes: []uint16{0xD002},
+		check:   checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:   "PC wraps past the end of memory",
+		modes:  []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = false },
+		before: machineState{PC: ptr[uint16](0x1000), Memory: map[uint16]uint8{0x000: 0x61, 0x001: 0x05}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0x002), V: map[int]uint8{1: 5}},
+		check:  checkOutOfRange(OutOfRangeAccess{PC: 0x1000, Address: 0x1000}),
+	},
+	{
+		name:   "PC faults past the end of memory",
+		modes:  []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = true },
+		before: machineState{PC: ptr[uint16](0x1000), Memory: map[uint16]uint8{0x000: 0x61, 0x001: 0x05}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0x1000), V: map[int]uint8{1: 0}},
+		check:  checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:   "an instruction straddling the end of memory wraps",
+		modes:  []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = false },
+		before: machineState{PC: ptr[uint16](0xFFF), Memory: map[uint16]uint8{0xFFF: 0x61, 0x000: 0x05}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0x1001), V: map[int]uint8{1: 5}},
+		check:  checkOutOfRange(OutOfRangeAccess{PC: 0xFFF, Address: 0x1000}),
+	},
+	{
+		name:   "an instruction straddling the end of memory faults",
+		modes:  []InterpreterMode{MODE_CHIP8, MODE_SUPERCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = true },
+		before: machineState{PC: ptr[uint16](0xFFF), Memory: map[uint16]uint8{0xFFF: 0x61, 0x000: 0x05}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0xFFF), V: map[int]uint8{1: 0}},
+		check:  checkFault(ErrMemoryOutOfRange),
+	},
+	{
+		name:   "F000 reads its address from the start of memory",
+		modes:  []InterpreterMode{MODE_XOCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = false },
+		before: machineState{PC: ptr[uint16](0xFFFE), Memory: map[uint16]uint8{0xFFFE: 0xF0, 0xFFFF: 0x00, 0x0000: 0x12, 0x0001: 0x34}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0x0002), I: ptr[uint16](0x1234)},
+		check:  checkOutOfRange(OutOfRangeAccess{PC: 0xFFFE, Opcode: 0xF000, Address: 0x10000}),
+	},
+	{
+		name:   "F000 faults when its address is past the end of memory",
+		modes:  []InterpreterMode{MODE_XOCHIP},
+		quirks: func(q *Quirks) { q.MemoryFault = true },
+		before: machineState{PC: ptr[uint16](0xFFFE), Memory: map[uint16]uint8{0xFFFE: 0xF0, 0xFFFF: 0x00, 0x0000: 0x12, 0x0001: 0x34}},
+		steps:  1,
+		after:  machineState{PC: ptr[uint16](0xFFFE), I: ptr[uint16](0)},
+		check:  checkFault(ErrMemoryOutOfRange),
+	},
+
+	// Unknown opcodes
+	{
+		name:    "unknown 5XYN opcodes fault",
+		opcodes: []uint16{0x5121},
+		after:   machineState{PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "unknown 8XYN opcodes fault",
+		opcodes: []uint16{0x8128},
+		after:   machineState{PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "unknown EXNN opcodes fault",
+		opcodes: []uint16{0xE1FF},
+		after:   machineState{PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+	{
+		name:    "unknown FXNN opcodes fault",
+		opcodes: []uint16{0xF1FF},
+		after:   machineState{PC: ptr[uint16](0x200)},
+		check:   checkFault(ErrUnknownOpcode),
+	},
+}
+
+// checkFault checks that the last instruction raised a fault
+func checkFault(expected error) func(t *testing.T, m *Machine) {
+	return func(t *testing.T, m *Machine) {
+		if m.fault == nil || !errors.Is(m.fault, expected) {
+			t.Errorf("fault = %v, expected %v", m.fault, expected)
+		}
+	}
+}
+
+// checkOutOfRange checks that the only out of range memory access recorded is the expected one
+func checkOutOfRange(expected OutOfRangeAccess) func(t *testing.T, m *Machine) {
+	return func(t *testing.T, m *Machine) {
+		if len(m.outOfRange) != 1 || m.outOfRange[0] != expected {
+			t.Errorf("out of range accesses = %v, expected [%v]", m.outOfRange, expected)
+		}
+	}
+}
+
+func TestOpcodes(t *testing.T) {
+	for _, test := range opcodeTests {
+		modes := test.modes
+		if modes == nil {
+			modes = allModes
+		}
+		for _, mode := range modes {
+			t.Run(fmt.Sprintf("%s/%s", test.name, modeNames[mode]), func(t *testing.T) {
+				m := New(mode)
+				quirks := DefaultQuirks(mode)
+				if test.quirks != nil {
+					test.quirks(&quirks)
+				}
+				m.SetQuirks(quirks)
+
+				for i, opcode := range test.opcodes {
+					m.memory[MEM_ROM_START+2*i] = uint8(opcode >> 8)
+					m.memory[MEM_ROM_START+2*i+1] = uint8(opcode)
+				}
+				setState(m, test.before)
+
+				steps := test.steps
+				if steps == 0 {
+					steps = len(test.opcodes)
+				}
+				for range steps {
+					m.vblank = !test.noVblank
+					m.step()
+				}
+
+				checkState(t, m, test.after)
+				if test.check != nil {
+					test.check(t, m)
+				} else {
+					if m.fault != nil {
+						t.Errorf("unexpected fault: %v", m.fault)
+					}
+					if len(m.outOfRange) > 0 {
+						t.Errorf("unexpected out of range memory accesses: %v", m.outOfRange)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestAwaitKeyRelease checks that FX0A with the key release quirk only takes a key once it has been released
+func TestAwaitKeyRelease(t *testing.T) {
+	m := New(MODE_CHIP8)
+	m.SetQuirks(Quirks{KeyRelease: true})
+	m.memory[MEM_ROM_START] = 0xF1
+	m.memory[MEM_ROM_START+1] = 0x0A
+
+	m.SetKey(7, true)
+	m.step()
+	m.step()
+	checkState(t, m, machineState{PC: ptr[uint16](0x200), V: map[int]uint8{1: 0}})
+
+	m.SetKey(7, false)
+	m.step()
+	checkState(t, m, machineState{PC: ptr[uint16](0x202), V: map[int]uint8{1: 7}})
+}
+
+// setState applies the parts of a machineState that have been set to the Machine
+func setState(m *Machine, state machineState) {
+	if state.Hires != nil {
+		m.setHires(*state.Hires)
+	}
+	for x, value := range state.V {
+		m.registers[x] = value
+	}
+	if state.I != nil {
+		m.indexRegister = *state.I
+	}
+	if state.PC != nil {
+		m.pc = *state.PC
+	}
+	if state.Stack != nil {
+		m.stack.stack = slices.Clone(state.Stack)
+	}
+	if state.DT != nil {
+		m.delayTimer = *state.DT
+	}
+	if state.ST != nil {
+		m.soundTimer = *state.ST
+	}
+	for address, value := range state.Memory {
+		m.memory[address] = value
+	}
+	for position, value := range state.Pixels {
+		m.display[position[0]][position[1]] = value
+	}
+	for _, key := range state.Keys {
+		m.input[key] = true
+	}
+	if state.Planes != nil {
+		m.planes = *state.Planes
+	}
+	if state.Pitch != nil {
+		m.pitch = *state.Pitch
+	}
+	for i, value := range state.RPLFlags {
+		m.rplFlags[i] = value
+	}
+	if state.Halted != nil {
+		m.halted = *state.Halted
+	}
+}
+
+// checkState reports an error for each part of the Machine that doesn't match the parts of a machineState that have been set
+func checkState(t *testing.T, m *Machine, expected machineState) {
+	t.Helper()
+	for x, value := range expected.V {
+		if m.registers[x] != value {
+			t.Errorf("V%X = %02X, expected %02X", x, m.registers[x], value)
+		}
+	}
+	if expected.I != nil && m.indexRegister != *expected.I {
+		t.Errorf("I = %04X, expected %04X", m.indexRegister, *expected.I)
+	}
+	if expected.PC != nil && m.pc != *expected.PC {
+		t.Errorf("PC = %04X, expected %04X", m.pc, *expected.PC)
+	}
+	if expected.Stack != nil && !slices.Equal(m.stack.stack, expected.Stack) {
+		t.Errorf("stack = %04X, expected %04X", m.stack.stack, expected.Stack)
+	}
+	if expected.DT != nil && m.delayTimer != *expected.DT {
+		t.Errorf("delay timer = %02X, expected %02X", m.delayTimer, *expected.DT)
+	}
+	if expected.ST != nil && m.soundTimer != *expected.ST {
+		t.Errorf("sound timer = %02X, expected %02X", m.soundTimer, *expected.ST)
+	}
+	for address, value := range expected.Memory {
+		if m.memory[address] != value {
+			t.Errorf("memory[%04X] = %02X, expected %02X", address, m.memory[address], value)
+		}
+	}
+	for position, value := range expected.Pixels {
+		if m.display[position[0]][position[1]] != value {
+			t.Errorf("pixel %v = %d, expected %d", position, m.display[position[0]][position[1]], value)
+		}
+	}
+	if expected.Hires != nil {
+		width, height := DISPLAY_WIDTH, DISPLAY_HEIGHT
+		if *expected.Hires {
+			width, height = HIRES_DISPLAY_WIDTH, HIRES_DISPLAY_HEIGHT
+		}
+		if m.hires != *expected.Hires || len(m.display) != width || len(m.display[0]) != height {
+			t.Errorf("hires = %t with a %dx%d display, expected %t with %dx%d", m.hires, len(m.display), len(m.display[0]), *expected.Hires, width, height)
+		}
+	}
+	if expected.Planes != nil && m.planes != *expected.Planes {
+		t.Errorf("planes = %d, expected %d", m.planes, *expected.Planes)
+	}
+	if expected.Pitch != nil && m.pitch != *expected.Pitch {
+		t.Errorf("pitch = %d, expected %d", m.pitch, *expected.Pitch)
+	}
+	for i, value := range expected.RPLFlags {
+		if m.rplFlags[i] != value {
+			t.Errorf("RPL flag %d = %02X, expected %02X", i, m.rplFlags[i], value)
+		}
+	}
+	if expected.Halted != nil && m.halted != *expected.Halted {
+		t.Errorf("halted = %t, expected %t", m.halted, *expected.Halted)
+	}
+}
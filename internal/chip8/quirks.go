@@ -0,0 +1,70 @@
+package chip8
+
+// Quirks toggles the behaviours that differ between CHIP-8 platforms.
+// See https://github.com/Timendus/chip8-test-suite#quirks-test for details on each.
+type Quirks struct {
+	// VFReset clears VF after the 8XY1, 8XY2, and 8XY3 logic operations
+	VFReset bool
+	// Shifting makes 8XY6 and 8XYE shift VX in place, instead of shifting VY into VX
+	Shifting bool
+	// MemoryIncrement makes FX55 and FX65 leave I pointing past the last register stored or fetched
+	MemoryIncrement bool
+	// DisplayWait limits DXYN to one sprite draw per frame, by waiting for the vertical blank
+	DisplayWait bool
+	// Clipping cuts sprites off at the display edges, instead of wrapping them around
+	Clipping bool
+	// Jumping makes BNNN behave as BXNN, jumping to XNN plus VX
+	Jumping bool
+	// KeyRelease makes FX0A wait for a key to be pressed and released, instead of just pressed
+	KeyRelease bool
+	// MemoryFault makes an instruction that reads or writes past the end of memory fault,
+	// instead of wrapping around to the start of memory
+	MemoryFault bool
+	// StackDepth is the most subroutine calls that can be nested before the stack overflows, or 0 for no limit
+	StackDepth int
+}
+
+// DefaultQuirks returns the quirks of the original platform for each mode
+func DefaultQuirks(mode InterpreterMode) Quirks {
+	switch mode {
+	case MODE_SUPERCHIP:
+		return Quirks{
+			Shifting: true,
+			Clipping: true,
+			Jumping:  true,
+			// The HP48's memory past the interpreter's 4K isn't the ROM's to use, so nothing there can be relied on
+			MemoryFault: true,
+			StackDepth:  STACK_DEPTH_SUPERCHIP,
+		}
+	case MODE_XOCHIP:
+		return Quirks{
+			MemoryIncrement: true,
+			StackDepth:      STACK_DEPTH_SUPERCHIP,
+		}
+	default:
+		// COSMAC VIP, whose address decoding wraps memory around
+		return Quirks{
+			VFReset:         true,
+			MemoryIncrement: true,
+			DisplayWait:     true,
+			Clipping:        true,
+			KeyRelease:      true,
+			StackDepth:      STACK_DEPTH_COSMAC,
+		}
+	}
+}
+
+// Quirks returns the quirks the Machine is currently using
+func (m *Machine) Quirks() Quirks {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quirks
+}
+
+// SetQuirks overrides the quirks set by the last Reset
+func (m *Machine) SetQuirks(quirks Quirks) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quirks = quirks
+	m.stack.depth = quirks.StackDepth
+}
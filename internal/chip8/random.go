@@ -0,0 +1,37 @@
+package chip8
+
+import (
+	"math/rand/v2"
+)
+
+// NewSeed picks a seed for a Machine's random number generator, for when a run doesn't need to be repeatable
+func NewSeed() uint64 {
+	return rand.Uint64()
+}
+
+// Seed returns the seed the random number generator was last started from
+func (m *Machine) Seed() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.seed
+}
+
+// SetSeed starts the random number generator again from a seed, so that CXNN gives the same sequence of
+// numbers each time the same seed is used. The seed is kept across a Reset, which restarts the sequence
+func (m *Machine) SetSeed(seed uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seed = seed
+	m.reseed()
+}
+
+// reseed starts the random number generator from the seed. The caller must hold m.mu
+func (m *Machine) reseed() {
+	m.random = rand.NewPCG(m.seed, 0)
+}
+
+// randomByte returns the next random byte for CXNN, which can be any value from 0 to 255.
+// The caller must hold m.mu
+func (m *Machine) randomByte() uint8 {
+	return uint8(m.random.Uint64())
+}
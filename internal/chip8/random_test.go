@@ -0,0 +1,92 @@
+package chip8
+
+import (
+	"slices"
+	"testing"
+)
+
+// randomBytes runs CXFF count times, returning each value drawn. A frame passes between each draw
+func randomBytes(t *testing.T, m *Machine, count int) []uint8 {
+	t.Helper()
+	rom := make([]byte, 0, count*2)
+	for range count {
+		rom = append(rom, 0xC1, 0xFF)
+	}
+	quirks := m.Quirks()
+	err := m.LoadRomBytes(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetQuirks(quirks)
+
+	values := make([]uint8, count)
+	for i := range values {
+		if reason := m.RunFrame(1); reason != STOP_NONE {
+			t.Fatalf("RunFrame() = %d at draw %d", reason, i)
+		}
+		values[i] = m.Registers().V[1]
+	}
+	return values
+}
+
+func TestSeedIsRepeatable(t *testing.T) {
+	newMachine := func() *Machine {
+		m := New(MODE_CHIP8)
+		m.SetSeed(1234)
+		return m
+	}
+	first := randomBytes(t, newMachine(), 64)
+	second := randomBytes(t, newMachine(), 64)
+	if !slices.Equal(first, second) {
+		t.Errorf("the same seed gave %v, then %v", first, second)
+	}
+
+	other := New(MODE_CHIP8)
+	other.SetSeed(5678)
+	if slices.Equal(first, randomBytes(t, other, 64)) {
+		t.Errorf("different seeds gave the same numbers")
+	}
+}
+
+func TestRandomCoversEveryByte(t *testing.T) {
+	m := New(MODE_CHIP8)
+	m.SetSeed(1)
+	seen := map[uint8]bool{}
+	for range 10000 {
+		seen[m.randomByte()] = true
+	}
+	if len(seen) != 256 {
+		t.Errorf("drew %d distinct values, expected all 256", len(seen))
+	}
+}
+
+func TestResetRestartsSequence(t *testing.T) {
+	m := New(MODE_CHIP8)
+	m.SetSeed(42)
+	first := randomBytes(t, m, 16)
+	m.Reset(MODE_CHIP8)
+	if m.Seed() != 42 {
+		t.Errorf("Seed() = %d after Reset, expected 42 to be kept", m.Seed())
+	}
+	if second := randomBytes(t, m, 16); !slices.Equal(first, second) {
+		t.Errorf("after Reset, drew %v, expected %v again", second, first)
+	}
+}
+
+func TestStateKeepsRandom(t *testing.T) {
+	m := New(MODE_CHIP8)
+	m.SetSeed(99)
+	randomBytes(t, m, 8)
+	state := m.SaveState()
+	expected := []uint8{m.randomByte(), m.randomByte(), m.randomByte()}
+
+	restored := New(MODE_CHIP8)
+	err := restored.LoadState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := []uint8{restored.randomByte(), restored.randomByte(), restored.randomByte()}
+	if !slices.Equal(actual, expected) || restored.Seed() != 99 {
+		t.Errorf("restored state drew %v, expected %v", actual, expected)
+	}
+}
@@ -0,0 +1,125 @@
+package chip8
+
+import "bytes"
+
+// REWIND_STATE_OVERHEAD roughly covers the parts of a State that aren't counted separately, such as the registers
+const REWIND_STATE_OVERHEAD = 256
+
+// RewindBuffer is a ring buffer of the most recent States, oldest being overwritten first.
+// A state shares its memory with the state before it when nothing in memory has changed, and the oldest states
+// are dropped early to keep the buffer within its byte budget. It is not safe for concurrent use
+type RewindBuffer struct {
+	states []State
+	// sizes are the approximate bytes held by each state, not counting memory shared with the state before it
+	sizes []int
+	// newest is the index of the most recently pushed state
+	newest int
+	count  int
+
+	bytes    int
+	maxBytes int
+}
+
+// NewRewindBuffer creates a RewindBuffer holding up to capacity states, in no more than about maxBytes
+func NewRewindBuffer(capacity int, maxBytes int) *RewindBuffer {
+	return &RewindBuffer{
+		states:   make([]State, capacity),
+		sizes:    make([]int, capacity),
+		newest:   -1,
+		maxBytes: maxBytes,
+	}
+}
+
+// Push records a state, discarding the oldest states if the buffer is full or over its byte budget.
+// The state must not be modified afterwards, as its memory may be shared with the next state pushed
+func (r *RewindBuffer) Push(state State) {
+	if len(r.states) == 0 {
+		return
+	}
+	size := stateSize(state)
+	if r.count > 0 {
+		previous := r.states[r.newest]
+		if bytes.Equal(previous.Memory, state.Memory) {
+			state.Memory = previous.Memory
+			size -= len(state.Memory)
+		}
+	}
+
+	if r.count == len(r.states) {
+		r.dropOldest()
+	}
+	r.newest = (r.newest + 1) % len(r.states)
+	r.states[r.newest] = state
+	r.sizes[r.newest] = size
+	r.bytes += size
+	r.count++
+
+	// Always keep the newest state, however large it is
+	for r.bytes > r.maxBytes && r.count > 1 {
+		r.dropOldest()
+	}
+}
+
+// Pop removes and returns the most recently pushed state, or false if the buffer is empty
+func (r *RewindBuffer) Pop() (State, bool) {
+	if r.count == 0 {
+		return State{}, false
+	}
+	state := r.states[r.newest]
+	r.bytes -= r.sizes[r.newest]
+	// Release the state's memory, rather than holding on to it until it's overwritten
+	r.states[r.newest] = State{}
+	r.sizes[r.newest] = 0
+	r.newest = (r.newest - 1 + len(r.states)) % len(r.states)
+	r.count--
+	return state, true
+}
+
+// Len returns the number of states in the buffer
+func (r *RewindBuffer) Len() int {
+	return r.count
+}
+
+// Bytes returns roughly how many bytes the states in the buffer hold on to
+func (r *RewindBuffer) Bytes() int {
+	return r.bytes
+}
+
+// Clear discards every state in the buffer
+func (r *RewindBuffer) Clear() {
+	clear(r.states)
+	clear(r.sizes)
+	r.newest = -1
+	r.count = 0
+	r.bytes = 0
+}
+
+// dropOldest discards the oldest state. Any memory it shared with the state after it is now held by that state
+func (r *RewindBuffer) dropOldest() {
+	oldest := (r.newest - r.count + 1 + len(r.states)) % len(r.states)
+	if r.count > 1 {
+		next := (oldest + 1) % len(r.states)
+		if sharesMemory(r.states[oldest], r.states[next]) {
+			r.sizes[next] += len(r.states[next].Memory)
+			r.sizes[oldest] -= len(r.states[oldest].Memory)
+		}
+	}
+	r.bytes -= r.sizes[oldest]
+	r.states[oldest] = State{}
+	r.sizes[oldest] = 0
+	r.count--
+}
+
+// sharesMemory reports whether two states hold the very same memory slice
+func sharesMemory(a, b State) bool {
+	return len(a.Memory) > 0 && len(b.Memory) > 0 && &a.Memory[0] == &b.Memory[0]
+}
+
+// stateSize estimates how many bytes a state holds on to
+func stateSize(state State) int {
+	size := REWIND_STATE_OVERHEAD + len(state.Memory) + len(state.Stack)*2 + len(state.Random)
+	for _, column := range state.Display {
+		size += len(column)
+	}
+	return size
+}
@@ -0,0 +1,97 @@
+package chip8
+
+import "testing"
+
+// rewindState is a small State, marked by its PC, with memory filled with fill
+func rewindState(pc uint16, fill byte) State {
+	memory := make([]byte, 64)
+	for i := range memory {
+		memory[i] = fill
+	}
+	return State{PC: pc, Memory: memory}
+}
+
+func TestRewindBufferOrder(t *testing.T) {
+	r := NewRewindBuffer(3, 1<<20)
+	for pc := range uint16(5) {
+		r.Push(rewindState(pc, byte(pc)))
+	}
+	if r.Len() != 3 {
+		t.Fatalf("Len() = %d, expected the capacity of 3", r.Len())
+	}
+	// The oldest states were overwritten, and the rest come back newest first
+	for _, expected := range []uint16{4, 3, 2} {
+		state, ok := r.Pop()
+		if !ok || state.PC != expected {
+			t.Fatalf("Pop() = PC %d, %v, expected PC %d", state.PC, ok, expected)
+		}
+	}
+	if _, ok := r.Pop(); ok || r.Len() != 0 || r.Bytes() != 0 {
+		t.Errorf("Pop() on an empty buffer = %v, with %d states and %d bytes left", ok, r.Len(), r.Bytes())
+	}
+
+	// The buffer carries on working after being emptied
+	r.Push(rewindState(9, 9))
+	if state, ok := r.Pop(); !ok || state.PC != 9 {
+		t.Errorf("Pop() = PC %d, %v, expected PC 9", state.PC, ok)
+	}
+}
+
+func TestRewindBufferSharesMemory(t *testing.T) {
+	r := NewRewindBuffer(10, 1<<20)
+	r.Push(rewindState(0, 1))
+	one := r.Bytes()
+	r.Push(rewindState(1, 1))
+	if shared := r.Bytes() - one; shared != one-64 {
+		t.Errorf("an unchanged state added %d bytes, expected %d without its memory", shared, one-64)
+	}
+	r.Push(rewindState(2, 2))
+	if r.Bytes() != 3*one-64 {
+		t.Errorf("Bytes() = %d, expected %d with only one state sharing memory", r.Bytes(), 3*one-64)
+	}
+
+	// Dropping the oldest state hands its memory over to the state sharing it
+	r = NewRewindBuffer(2, 1<<20)
+	r.Push(rewindState(0, 1))
+	r.Push(rewindState(1, 1))
+	r.Push(rewindState(2, 1))
+	if r.Bytes() != 2*one-64 {
+		t.Errorf("Bytes() = %d, expected %d for two states sharing memory", r.Bytes(), 2*one-64)
+	}
+	state, _ := r.Pop()
+	older, _ := r.Pop()
+	if state.PC != 2 || older.PC != 1 || older.Memory[0] != 1 {
+		t.Errorf("popped PC %d then %d, expected 2 then 1 with its memory intact", state.PC, older.PC)
+	}
+}
+
+func TestRewindBufferByteBudget(t *testing.T) {
+	size := stateSize(rewindState(0, 0))
+	r := NewRewindBuffer(100, 3*size)
+	for pc := range uint16(10) {
+		r.Push(rewindState(pc, byte(pc)))
+	}
+	if r.Len() != 3 || r.Bytes() > 3*size {
+		t.Errorf("%d states in %d bytes, expected 3 states within the budget of %d", r.Len(), r.Bytes(), 3*size)
+	}
+	if state, _ := r.Pop(); state.PC != 9 {
+		t.Errorf("Pop() = PC %d, expected the newest state to be kept", state.PC)
+	}
+
+	// A single state is kept even if it's over budget
+	r = NewRewindBuffer(100, 1)
+	r.Push(rewindState(0, 0))
+	if r.Len() != 1 {
+		t.Errorf("Len() = %d, expected the newest state to be kept", r.Len())
+	}
+}
+
+func TestRewindBufferClear(t *testing.T) {
+	r := NewRewindBuffer(3, 1<<20)
+	r.Push(rewindState(0, 0))
+	r.Push(rewindState(1, 1))
+	r.Clear()
+	if _, ok := r.Pop(); ok || r.Bytes() != 0 {
+		t.Errorf("Pop() after Clear() = %v with %d bytes, expected an empty buffer", ok, r.Bytes())
+	}
+}
@@ -0,0 +1,59 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLoadRom(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     InterpreterMode
+		size     int
+		expected error
+	}{
+		{"empty", MODE_CHIP8, 0, ErrRomEmpty},
+		{"fits in CHIP-8", MODE_CHIP8, MEM_SIZE - MEM_ROM_START, nil},
+		{"too large for CHIP-8", MODE_CHIP8, MEM_SIZE - MEM_ROM_START + 1, ErrRomTooLarge},
+		{"too large for SUPER-CHIP", MODE_SUPERCHIP, MEM_SIZE - MEM_ROM_START + 1, ErrRomTooLarge},
+		{"fits in XO-CHIP", MODE_XOCHIP, MEM_SIZE_XOCHIP - MEM_ROM_START, nil},
+		{"too large for XO-CHIP", MODE_XOCHIP, MEM_SIZE_XOCHIP - MEM_ROM_START + 1, ErrRomTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rom := make([]byte, test.size)
+			for i := range rom {
+				rom[i] = byte(i)
+			}
+			m := New(test.mode)
+			// Reading a byte at a time checks that short reads don't truncate the ROM
+			err := m.LoadRom(iotest.OneByteReader(bytes.NewReader(rom)))
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("LoadRom() = %v, expected %v", err, test.expected)
+			}
+			if err != nil {
+				if m.RomSize() != 0 || m.memory[MEM_ROM_START+1] != 0 {
+					t.Errorf("memory was changed by a ROM that failed to load")
+				}
+				return
+			}
+			if m.RomSize() != test.size || !bytes.Equal(m.memory[MEM_ROM_START:], rom) {
+				t.Errorf("loaded %d bytes, expected all %d", m.RomSize(), test.size)
+			}
+		})
+	}
+}
+
+// TestLoadRomReadError checks that an error partway through a ROM isn't mistaken for the end of it
+func TestLoadRomReadError(t *testing.T) {
+	m := New(MODE_CHIP8)
+	err := m.LoadRom(iotest.TimeoutReader(bytes.NewReader([]byte{0x00, 0xE0})))
+	if !errors.Is(err, ErrRomRead) {
+		t.Fatalf("LoadRom() = %v, expected %v", err, ErrRomRead)
+	}
+	if m.RomSize() != 0 {
+		t.Errorf("loaded %d bytes of a ROM that failed to read", m.RomSize())
+	}
+}
@@ -0,0 +1,44 @@
+package chip8
+
+import "errors"
+
+const (
+	STACK_DEPTH_COSMAC    = 12
+	STACK_DEPTH_SUPERCHIP = 16
+)
+
+var (
+	ErrStackOverflow  = errors.New("stack overflow")
+	ErrStackUnderflow = errors.New("stack underflow")
+)
+
+// Stack holds subroutine return addresses, up to a limited depth
+type Stack struct {
+	stack []uint16
+	// depth is the most addresses the stack can hold, or 0 for no limit
+	depth int
+}
+
+// NewStack creates an empty stack that holds up to depth addresses, or any number if depth is 0
+func NewStack(depth int) Stack {
+	return Stack{depth: depth}
+}
+
+// Push adds an address to the top of the stack, or returns ErrStackOverflow if the stack is full
+func (s *Stack) Push(value uint16) error {
+	if s.depth > 0 && len(s.stack) >= s.depth {
+		return ErrStackOverflow
+	}
+	s.stack = append(s.stack, value)
+	return nil
+}
+
+// Pop removes the address from the top of the stack, or returns ErrStackUnderflow if the stack is empty
+func (s *Stack) Pop() (uint16, error) {
+	if len(s.stack) == 0 {
+		return 0, ErrStackUnderflow
+	}
+	value := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	return value, nil
+}
@@ -0,0 +1,246 @@
+package chip8
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+)
+
+const (
+	SAVE_STATE_MAGIC = "CHIP8STATE"
+	// SAVE_STATE_VERSION 2 added the stack depth and memory fault quirks, and the random number state
+	SAVE_STATE_VERSION = 2
+)
+
+var (
+	ErrNotSaveState     = errors.New("not a CHIP-8 save state")
+	ErrSaveStateVersion = errors.New("unsupported save state version")
+	ErrInvalidSaveState = errors.New("invalid save state")
+)
+
+// State is a complete snapshot of a Machine, from which it can be restored exactly
+type State struct {
+	Mode   InterpreterMode
+	Quirks Quirks
+
+	Memory        []byte
+	Registers     []uint8
+	PC            uint16
+	IndexRegister uint16
+	Stack         []uint16
+
+	DelayTimer uint8
+	SoundTimer uint8
+
+	Display [][]uint8
+	Planes  uint8
+	Hires   bool
+	Input   []bool
+	// KeyAwaitingRelease is the key FX0A is waiting to be released, or -1 if there isn't one
+	KeyAwaitingRelease int
+
+	AudioPattern [AUDIO_PATTERN_SIZE]byte
+	Pitch        uint8
+
+	RPLFlags [16]uint8
+	Halted   bool
+	RomHash  string
+	RomSize  int
+
+	Seed uint64
+	// Random is the generator's state, which is empty in save states from before it was recorded
+	Random []byte
+}
+
+// SaveState takes a snapshot of the Machine's current state
+func (m *Machine) SaveState() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := State{
+		Mode:   m.mode,
+		Quirks: m.quirks,
+
+		Memory:        append([]byte(nil), m.memory...),
+		Registers:     append([]uint8(nil), m.registers...),
+		PC:            m.pc,
+		IndexRegister: m.indexRegister,
+		Stack:         append([]uint16(nil), m.stack.stack...),
+
+		DelayTimer: m.delayTimer,
+		SoundTimer: m.soundTimer,
+
+		Display:            make([][]uint8, len(m.display)),
+		Planes:             m.planes,
+		Hires:              m.hires,
+		Input:              append([]bool(nil), m.input...),
+		KeyAwaitingRelease: -1,
+
+		AudioPattern: m.audioPattern,
+		Pitch:        m.pitch,
+
+		RPLFlags: m.rplFlags,
+		Halted:   m.halted,
+		RomHash:  m.romHash,
+		RomSize:  m.romSize,
+
+		Seed: m.seed,
+	}
+	// Marshalling a PCG never fails
+	state.Random, _ = m.random.MarshalBinary()
+	for x := range m.display {
+		state.Display[x] = append([]uint8(nil), m.display[x]...)
+	}
+	if m.keyAwaitingRelease != nil {
+		state.KeyAwaitingRelease = *m.keyAwaitingRelease
+	}
+	return state
+}
+
+// LoadState restores the Machine to a snapshot taken by SaveState
+func (m *Machine) LoadState(state State) error {
+	err := state.validate()
+	if err != nil {
+		return err
+	}
+	random := rand.NewPCG(state.Seed, 0)
+	if len(state.Random) > 0 {
+		err = random.UnmarshalBinary(state.Random)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSaveState, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mode = state.Mode
+	m.quirks = state.Quirks
+
+	m.memory = append([]byte(nil), state.Memory...)
+	m.registers = append([]uint8(nil), state.Registers...)
+	m.pc = state.PC
+	m.indexRegister = state.IndexRegister
+	m.stack = Stack{stack: append([]uint16(nil), state.Stack...), depth: state.Quirks.StackDepth}
+
+	m.delayTimer = state.DelayTimer
+	m.soundTimer = state.SoundTimer
+
+	m.display = make([][]uint8, len(state.Display))
+	for x := range state.Display {
+		m.display[x] = append([]uint8(nil), state.Display[x]...)
+	}
+	m.planes = state.Planes
+	m.hires = state.Hires
+	m.input = append([]bool(nil), state.Input...)
+	m.keyAwaitingRelease = nil
+	if state.KeyAwaitingRelease >= 0 {
+		key := state.KeyAwaitingRelease
+		m.keyAwaitingRelease = &key
+	}
+
+	m.audioPattern = state.AudioPattern
+	m.pitch = state.Pitch
+
+	m.rplFlags = state.RPLFlags
+	m.halted = state.Halted
+	m.romHash = state.RomHash
+	m.romSize = state.RomSize
+
+	m.seed = state.Seed
+	m.random = random
+
+	m.vblank = false
+	m.waitingOnFrame = false
+	m.runTarget = nil
+	m.fault = nil
+	m.outOfRange = nil
+	return nil
+}
+
+// validate checks that the state can be safely loaded into a Machine
+func (state State) validate() error {
+	expectedMemorySize := memorySize(state.Mode)
+	width, height := DISPLAY_WIDTH, DISPLAY_HEIGHT
+	if state.Hires {
+		width, height = HIRES_DISPLAY_WIDTH, HIRES_DISPLAY_HEIGHT
+	}
+
+	switch {
+	case state.Mode < MODE_CHIP8 || state.Mode > MODE_XOCHIP:
+		return fmt.Errorf("%w: unknown mode %d", ErrInvalidSaveState, state.Mode)
+	case state.Quirks.StackDepth < 0:
+		return fmt.Errorf("%w: negative stack depth %d", ErrInvalidSaveState, state.Quirks.StackDepth)
+	case state.Quirks.StackDepth > 0 && len(state.Stack) > state.Quirks.StackDepth:
+		return fmt.Errorf("%w: %d addresses on the stack, but it only holds %d", ErrInvalidSaveState, len(state.Stack), state.Quirks.StackDepth)
+	case len(state.Memory) != expectedMemorySize:
+		return fmt.Errorf("%w: memory is %d bytes, expected %d", ErrInvalidSaveState, len(state.Memory), expectedMemorySize)
+	case len(state.Registers) != 16:
+		return fmt.Errorf("%w: %d registers, expected 16", ErrInvalidSaveState, len(state.Registers))
+	case len(state.Input) != 16:
+		return fmt.Errorf("%w: %d keys, expected 16", ErrInvalidSaveState, len(state.Input))
+	case state.KeyAwaitingRelease >= 16:
+		return fmt.Errorf("%w: awaiting release of unknown key %d", ErrInvalidSaveState, state.KeyAwaitingRelease)
+	case len(state.Display) != width:
+		return fmt.Errorf("%w: display is %d pixels wide, expected %d", ErrInvalidSaveState, len(state.Display), width)
+	}
+	for _, column := range state.Display {
+		if len(column) != height {
+			return fmt.Errorf("%w: display is %d pixels high, expected %d", ErrInvalidSaveState, len(column), height)
+		}
+	}
+	return nil
+}
+
+// WriteState encodes a State in the versioned save state format
+func WriteState(w io.Writer, state State) error {
+	_, err := io.WriteString(w, SAVE_STATE_MAGIC)
+	if err != nil {
+		return err
+	}
+	encoder := gob.NewEncoder(w)
+	err = encoder.Encode(SAVE_STATE_VERSION)
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(state)
+}
+
+// ReadState decodes a State written by WriteState, including those written by older versions
+func ReadState(r io.Reader) (State, error) {
+	var state State
+
+	magic := make([]byte, len(SAVE_STATE_MAGIC))
+	_, err := io.ReadFull(r, magic)
+	if err != nil || string(magic) != SAVE_STATE_MAGIC {
+		return state, ErrNotSaveState
+	}
+
+	decoder := gob.NewDecoder(r)
+	var version int
+	err = decoder.Decode(&version)
+	if err != nil {
+		return state, err
+	}
+	if version < 1 || version > SAVE_STATE_VERSION {
+		return state, fmt.Errorf("%w: %d", ErrSaveStateVersion, version)
+	}
+
+	err = decoder.Decode(&state)
+	if err != nil {
+		return state, err
+	}
+	state.migrate(version)
+	return state, nil
+}
+
+// migrate fills in what a save state from an older version didn't record
+func (state *State) migrate(version int) {
+	if version < 2 {
+		// Version 1 predates the stack depth, so take the mode's own. Memory always wrapped around then, so
+		// MemoryFault stays off, and the random number generator starts again from a seed of 0
+		state.Quirks.StackDepth = DefaultQuirks(state.Mode).StackDepth
+	}
+}
@@ -0,0 +1,181 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	m := New(MODE_XOCHIP)
+	m.SetQuirks(Quirks{StackDepth: 4, MemoryFault: true})
+	var buffer bytes.Buffer
+	err := WriteState(&buffer, m.SaveState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ReadState(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Mode != MODE_XOCHIP || state.Quirks != (Quirks{StackDepth: 4, MemoryFault: true}) {
+		t.Errorf("read back mode %d with quirks %+v, expected what was saved", state.Mode, state.Quirks)
+	}
+}
+
+// writeStateVersion writes a state as though by an older version of WriteState
+func writeStateVersion(w io.Writer, version int, state any) error {
+	_, err := io.WriteString(w, SAVE_STATE_MAGIC)
+	if err != nil {
+		return err
+	}
+	encoder := gob.NewEncoder(w)
+	err = encoder.Encode(version)
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(state)
+}
+
+// quirksVersion1 and stateVersion1 are the shape of Quirks and State when SAVE_STATE_VERSION was 1
+type quirksVersion1 struct {
+	VFReset         bool
+	Shifting        bool
+	MemoryIncrement bool
+	DisplayWait     bool
+	Clipping        bool
+	Jumping         bool
+	KeyRelease      bool
+}
+
+type stateVersion1 struct {
+	Mode   InterpreterMode
+	Quirks quirksVersion1
+
+	Memory        []byte
+	Registers     []uint8
+	PC            uint16
+	IndexRegister uint16
+	Stack         []uint16
+
+	DelayTimer uint8
+	SoundTimer uint8
+
+	Display            [][]uint8
+	Planes             uint8
+	Hires              bool
+	Input              []bool
+	KeyAwaitingRelease int
+
+	AudioPattern [AUDIO_PATTERN_SIZE]byte
+	Pitch        uint8
+
+	RPLFlags [16]uint8
+	Halted   bool
+	RomHash  string
+}
+
+func TestStateMigratesVersion1(t *testing.T) {
+	for _, mode := range allModes {
+		m := New(mode)
+		current := m.SaveState()
+		defaults := DefaultQuirks(mode)
+		state := stateVersion1{
+			Mode: mode,
+			Quirks: quirksVersion1{
+				VFReset:         defaults.VFReset,
+				Shifting:        defaults.Shifting,
+				MemoryIncrement: defaults.MemoryIncrement,
+				DisplayWait:     defaults.DisplayWait,
+				Clipping:        defaults.Clipping,
+				Jumping:         defaults.Jumping,
+				KeyRelease:      defaults.KeyRelease,
+			},
+			Memory:             current.Memory,
+			Registers:          current.Registers,
+			PC:                 0x204,
+			IndexRegister:      0x300,
+			Stack:              []uint16{0x202},
+			Display:            current.Display,
+			Input:              current.Input,
+			KeyAwaitingRelease: -1,
+		}
+		state.Registers[3] = 0x42
+
+		var buffer bytes.Buffer
+		err := writeStateVersion(&buffer, 1, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrated, err := ReadState(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Version 1 had no stack depth, so the mode's own is taken. Memory always wrapped, so it still does
+		expected := defaults
+		expected.MemoryFault = false
+		if migrated.Quirks != expected {
+			t.Errorf("%s: migrated quirks = %+v, expected %+v", modeNames[mode], migrated.Quirks, expected)
+		}
+		restored := New(mode)
+		err = restored.LoadState(migrated)
+		if err != nil {
+			t.Errorf("%s: migrated state doesn't load: %v", modeNames[mode], err)
+			continue
+		}
+		registers := restored.Registers()
+		if registers.PC != 0x204 || registers.I != 0x300 || registers.V[3] != 0x42 || len(registers.Stack) != 1 {
+			t.Errorf("%s: migrated state restored registers %+v, expected what was saved", modeNames[mode], registers)
+		}
+	}
+}
+
+func TestStateUnknownVersion(t *testing.T) {
+	for _, version := range []int{0, SAVE_STATE_VERSION + 1} {
+		var buffer bytes.Buffer
+		err := writeStateVersion(&buffer, version, New(MODE_CHIP8).SaveState())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ReadState(&buffer)
+		if !errors.Is(err, ErrSaveStateVersion) {
+			t.Errorf("version %d: error = %v, expected %v", version, err, ErrSaveStateVersion)
+		}
+	}
+}
+
+func TestStateValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(state *State)
+	}{
+		{"unknown mode", func(state *State) { state.Mode = MODE_XOCHIP + 1 }},
+		{"no mode", func(state *State) { state.Mode = MODE_NONE }},
+		{"negative stack depth", func(state *State) { state.Quirks.StackDepth = -1 }},
+		{"stack deeper than its depth", func(state *State) { state.Stack = make([]uint16, state.Quirks.StackDepth+1) }},
+		{"short memory", func(state *State) { state.Memory = state.Memory[:100] }},
+		{"missing registers", func(state *State) { state.Registers = state.Registers[:8] }},
+		{"unknown key awaiting release", func(state *State) { state.KeyAwaitingRelease = 16 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := New(MODE_CHIP8).SaveState()
+			test.modify(&state)
+			err := New(MODE_CHIP8).LoadState(state)
+			if !errors.Is(err, ErrInvalidSaveState) {
+				t.Errorf("LoadState() error = %v, expected %v", err, ErrInvalidSaveState)
+			}
+		})
+	}
+
+	// With no limit, the stack can hold any number of addresses
+	state := New(MODE_CHIP8).SaveState()
+	state.Quirks.StackDepth = 0
+	state.Stack = make([]uint16, 100)
+	err := New(MODE_CHIP8).LoadState(state)
+	if err != nil {
+		t.Errorf("LoadState() with an unlimited stack = %v, expected no error", err)
+	}
+}
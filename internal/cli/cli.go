@@ -0,0 +1,414 @@
+// Package cli is the command line, which runs ROMs headlessly, disassembles, and assembles without the UI.
+// The UI isn't imported here, so that builds without it need no display or SDL libraries
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/headless"
+	"github.com/greenrock64/chip8-interpreter/internal/octo"
+	"github.com/greenrock64/chip8-interpreter/internal/roms"
+	"github.com/greenrock64/chip8-interpreter/internal/sound"
+)
+
+// Startup is a ROM for the UI to start as soon as it opens, with the settings given on the command line
+type Startup struct {
+	Name   string
+	Rom    []byte
+	Mode   chip8.InterpreterMode
+	Quirks chip8.Quirks
+	// Seed is the random seed to start with, or nil to pick a new one
+	Seed                 *uint64
+	InstructionsPerFrame int
+}
+
+// OpenUI opens the UI with a ROM already running, returning once it's closed.
+// It's nil in builds without the UI, in which case ROMs can only be run headlessly
+var OpenUI func(startup Startup) error
+
+// commands are the command line subcommands, which run without opening the UI
+var commands = map[string]func(args []string) error{
+	"run":    runCommand,
+	"disasm": disasmCommand,
+	"asm":    asmCommand,
+	"roms":   romsCommand,
+}
+
+// RunCommand runs a command line subcommand, and returns the exit code for the process.
+// Returns false if args don't name a subcommand, in which case the UI should be started instead
+func RunCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return 0, false
+	}
+	err := command(args[1:])
+	if err == flag.ErrHelp {
+		return 2, true
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1, true
+	}
+	return 0, true
+}
+
+// parseArgs parses flags, which may come before or after the positional arguments, and checks that
+// exactly count positional arguments were given. The flag package has already reported any error it returns
+func parseArgs(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	var positional []string
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, flag.ErrHelp
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != count {
+		flags.Usage()
+		return nil, flag.ErrHelp
+	}
+	return positional, nil
+}
+
+// runCommand runs a ROM, either headlessly for a number of frames before dumping the machine's state,
+// or in the UI as though it had been opened from the File menu
+func runCommand(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8-interpreter run [-headless] [options] <rom file, library name, or - for stdin>")
+		flags.PrintDefaults()
+	}
+	isHeadless := flags.Bool("headless", false, "run without opening any windows, then dump the machine's state")
+	modeName := flags.String("mode", "chip8", "the platform the ROM was written for")
+	frames := flags.Int("frames", 600, "the number of frames to run for when headless, unless the ROM exits first")
+	instructions := flags.Int("ipf", headless.INSTRUCTIONS_PER_FRAME, "the number of instructions to run per frame")
+	displayPath := flags.String("display", "-", "where to dump the display when headless, as a .png image or text. - is stdout, and an empty path skips it")
+	registersPath := flags.String("registers", "-", "where to dump the registers when headless. - is stdout, and an empty path skips it")
+	memoryFault := flags.Bool("memory-fault", false, "fault on reading or writing past the end of memory, instead of wrapping around. Defaults to the mode's own setting")
+	seed := flags.Uint64("seed", 0, "the seed for CXNN's random numbers, so that a run can be repeated. A new seed is picked if not given")
+	stackDepth := flags.Int("stack-depth", 0, "the most subroutine calls that can be nested before the stack overflows, or 0 for no limit. Defaults to the mode's own platform")
+	audioPath := flags.String("audio", "", "where to record the buzzer when headless, as a .wav file. An empty path skips it")
+	memoryPath := flags.String("memory", "", "where to dump memory when headless, as raw .bin data or text. - is stdout, and an empty path skips it")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	// Nothing would run at all with no frames or no instructions per frame
+	if *frames <= 0 || *instructions <= 0 {
+		fmt.Fprintf(flags.Output(), "-frames and -ipf must be at least 1, got %d and %d\n", *frames, *instructions)
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	if *stackDepth < 0 {
+		fmt.Fprintf(flags.Output(), "-stack-depth can't be negative, got %d\n", *stackDepth)
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	if !*isHeadless && OpenUI == nil {
+		fmt.Fprintln(flags.Output(), "This build has no UI, so ROMs can only be run with -headless")
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	mode, err := parseMode(*modeName)
+	if err != nil {
+		return err
+	}
+
+	romPath := positional[0]
+	romFile, libraryRom, err := openRom(romPath)
+	if err != nil {
+		return err
+	}
+	defer romFile.Close()
+	// Library ROMs run in the mode they were written for, unless told otherwise
+	modeGiven, seedGiven, stackDepthGiven, memoryFaultGiven := false, false, false, false
+	flags.Visit(func(f *flag.Flag) {
+		modeGiven = modeGiven || f.Name == "mode"
+		seedGiven = seedGiven || f.Name == "seed"
+		stackDepthGiven = stackDepthGiven || f.Name == "stack-depth"
+		memoryFaultGiven = memoryFaultGiven || f.Name == "memory-fault"
+	})
+	if libraryRom != nil && !modeGiven {
+		mode = libraryRom.Mode
+	}
+	quirks := chip8.DefaultQuirks(mode)
+	if memoryFaultGiven {
+		quirks.MemoryFault = *memoryFault
+	}
+	if stackDepthGiven {
+		quirks.StackDepth = *stackDepth
+	}
+	if !*isHeadless {
+		// Check that the ROM loads before opening any windows, so that a bad ROM is reported here
+		rom, err := io.ReadAll(romFile)
+		if err != nil {
+			return fmt.Errorf("%s: %w: %w", romPath, chip8.ErrRomRead, err)
+		}
+		err = chip8.New(mode).LoadRomBytes(rom)
+		if err != nil {
+			return fmt.Errorf("%s: %w", romPath, err)
+		}
+		startup := Startup{
+			Name:                 romPath,
+			Rom:                  rom,
+			Mode:                 mode,
+			Quirks:               quirks,
+			InstructionsPerFrame: *instructions,
+		}
+		if seedGiven {
+			startup.Seed = seed
+		}
+		return OpenUI(startup)
+	}
+
+	if !seedGiven {
+		*seed = chip8.NewSeed()
+	}
+	m, err := headless.NewMachine(romFile, mode, quirks, *seed)
+	if err != nil {
+		return fmt.Errorf("%s: %w", romPath, err)
+	}
+	// The seed is reported, so that a run that went wrong can be repeated
+	fmt.Fprintf(os.Stderr, "Random seed %d\n", *seed)
+	var framesRun int
+	var samples []float32
+	if *audioPath != "" {
+		framesRun, samples = headless.RunWithAudio(m, *frames, *instructions, sound.NewBuzzer())
+	} else {
+		framesRun = headless.Run(m, *frames, *instructions)
+	}
+	fault := m.Fault()
+	if fault != nil {
+		fmt.Fprintf(os.Stderr, "ROM faulted after %d frames\n", framesRun)
+	} else if m.Halted() {
+		fmt.Fprintf(os.Stderr, "ROM exited after %d frames\n", framesRun)
+	} else {
+		fmt.Fprintf(os.Stderr, "Ran %d frames\n", framesRun)
+	}
+	for _, access := range m.OutOfRangeAccesses() {
+		fmt.Fprintf(os.Stderr, "Out of range memory access at %v\n", access)
+	}
+
+	err = writeDump(*displayPath, func(w io.Writer, ext string) error {
+		if ext == ".png" {
+			return headless.WriteDisplayPNG(w, m.Display(), 8)
+		}
+		_, err := io.WriteString(w, headless.DisplayText(m.Display()))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = writeDump(*registersPath, func(w io.Writer, ext string) error {
+		_, err := io.WriteString(w, headless.RegistersText(m.Registers()))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = writeDump(*audioPath, func(w io.Writer, ext string) error {
+		return sound.WriteWAV(w, samples)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeDump(*memoryPath, func(w io.Writer, ext string) error {
+		if ext == ".bin" {
+			_, err := w.Write(m.Memory())
+			return err
+		}
+		_, err := io.WriteString(w, headless.MemoryText(m.Memory()))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// The dumps are still written for a faulted ROM, to help find the bug, but the run has failed
+	if fault != nil {
+		return fault
+	}
+	return nil
+}
+
+// openRom opens a ROM file, or failing that, the library ROM with that name. A path of - reads the ROM from stdin.
+// The library ROM is returned as well when one is opened
+func openRom(path string) (io.ReadCloser, *roms.Rom, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil, nil
+	}
+	romFile, err := os.Open(path)
+	if err == nil {
+		return romFile, nil, nil
+	}
+	rom, ok := roms.Find(path)
+	if !ok {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("%w: %w", chip8.ErrRomNotFound, err)
+		}
+		return nil, nil, fmt.Errorf("%w: %w", chip8.ErrRomRead, err)
+	}
+	data, err := rom.Data()
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), &rom, nil
+}
+
+// writeDump writes part of the machine's state to a file, or stdout if the path is -. An empty path writes nothing.
+// The file's extension is passed on, so that the format can be picked to suit it
+func writeDump(path string, write func(w io.Writer, ext string) error) error {
+	if path == "" {
+		return nil
+	}
+	if path == "-" {
+		return write(os.Stdout, "")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file, strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// disasmCommand prints a ROM as annotated assembly
+func disasmCommand(args []string) error {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8-interpreter disasm [-mode chip8|superchip|xochip] <rom file, library name, or - for stdin>")
+		flags.PrintDefaults()
+	}
+	modeName := flags.String("mode", "chip8", "the platform the ROM was written for")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	mode, err := parseMode(*modeName)
+	if err != nil {
+		return err
+	}
+
+	romFile, _, err := openRom(positional[0])
+	if err != nil {
+		return err
+	}
+	defer romFile.Close()
+	rom, err := io.ReadAll(romFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", chip8.ErrRomRead, err)
+	}
+	err = chip8.DisassembleRom(os.Stdout, rom, mode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", positional[0], err)
+	}
+	return nil
+}
+
+// romsCommand lists the ROMs in the built in library
+func romsCommand(args []string) error {
+	flags := flag.NewFlagSet("roms", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8-interpreter roms")
+		flags.PrintDefaults()
+	}
+	_, err := parseArgs(flags, args, 0)
+	if err != nil {
+		return err
+	}
+
+	library := roms.Library()
+	if len(library) == 0 {
+		fmt.Println("No ROMs are built in")
+		return nil
+	}
+	for _, rom := range library {
+		fmt.Printf("%-16s %-10s %s\n", rom.Name, modeNames[rom.Mode], rom.Label())
+	}
+	return nil
+}
+
+// asmCommand assembles an Octo source file into a ROM
+func asmCommand(args []string) error {
+	flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip8-interpreter asm [-o <rom>] <source.8o>")
+		flags.PrintDefaults()
+	}
+	outputPath := flags.String("o", "", "where to write the ROM, defaulting to the source file with a .ch8 extension. - is stdout")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	sourcePath := positional[0]
+	rom, err := assembleFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	if *outputPath == "" {
+		*outputPath = strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath)) + ".ch8"
+	}
+	if *outputPath == "-" {
+		_, err := os.Stdout.Write(rom)
+		return err
+	}
+	return os.WriteFile(*outputPath, rom, 0644)
+}
+
+// assembleFile assembles an Octo source file, with any error pointing at the file, line, and column
+func assembleFile(path string) ([]byte, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return AssembleSource(path, source)
+}
+
+// AssembleSource assembles Octo source, prefixing any error with the name of the file it came from
+func AssembleSource(name string, source []byte) ([]byte, error) {
+	rom, err := octo.Assemble(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", name, err)
+	}
+	return rom, nil
+}
+
+// modeNames are the names of each hardware mode on the command line, as understood by parseMode
+var modeNames = map[chip8.InterpreterMode]string{
+	chip8.MODE_CHIP8:     "chip8",
+	chip8.MODE_SUPERCHIP: "superchip",
+	chip8.MODE_XOCHIP:    "xochip",
+}
+
+// parseMode reads a hardware mode name, as given on the command line
+func parseMode(name string) (chip8.InterpreterMode, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "")) {
+	case "chip8":
+		return chip8.MODE_CHIP8, nil
+	case "superchip", "schip":
+		return chip8.MODE_SUPERCHIP, nil
+	case "xochip":
+		return chip8.MODE_XOCHIP, nil
+	}
+	return chip8.MODE_NONE, fmt.Errorf("unknown hardware mode %q, expected chip8, superchip, or xochip", name)
+}
@@ -0,0 +1,151 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+// onFault is called when the interpreter stops on a fault, so that the UI can ask what to do about it
+var onFault = func(fault *chip8.Fault) {}
+
+// onDebuggerStop pauses the interpreter when a breakpoint or step target is reached, or an instruction faults
+func onDebuggerStop(reason chip8.StopReason) {
+	setPaused(true)
+	switch reason {
+	case chip8.STOP_BREAKPOINT:
+		fmt.Printf("Breakpoint hit at 0x%03X\n", machine.PC())
+	case chip8.STOP_TARGET:
+		fmt.Printf("Stopped at 0x%03X\n", machine.PC())
+	case chip8.STOP_FAULT:
+		reportFault(machine.Fault())
+	}
+}
+
+// reportFault marks the interpreter as faulted, and lets the UI know so that it can ask what to do about it
+func reportFault(fault *chip8.Fault) {
+	fmt.Printf("Paused on %v\n", fault)
+	interpreterLifecycle.Fault()
+	onFault(fault)
+}
+
+// reportStep prints where a single step ended up, or the fault it raised
+func reportStep() {
+	if fault := machine.Fault(); fault != nil {
+		reportFault(fault)
+		return
+	}
+	fmt.Printf("Stepped to 0x%03X\n", machine.PC())
+}
+
+// resumeInterpreter continues running from a pause, without any step target
+func resumeInterpreter() {
+	machine.CancelRunTarget()
+	setPaused(false)
+}
+
+// ignoreFault skips over the faulting instruction, and carries on running
+func ignoreFault() {
+	machine.IgnoreFault()
+	resumeInterpreter()
+}
+
+// debugFault stays paused at the faulting instruction, so that it can be inspected, and run again once fixed
+func debugFault() {
+	machine.ClearFault()
+	setPaused(true)
+	fmt.Printf("Paused at 0x%03X\n", machine.PC())
+}
+
+// resetAfterFault starts the ROM again from the beginning
+func resetAfterFault() {
+	err := restartRom()
+	if err != nil {
+		fmt.Println("failed to reset the ROM:", err)
+		return
+	}
+	setPaused(false)
+}
+
+// stepInstruction pauses the interpreter, and executes exactly one instruction
+func stepInstruction() {
+	setPaused(true)
+	machine.CancelRunTarget()
+	machine.Step()
+	reportStep()
+}
+
+// stepOver steps a single instruction, or runs a whole subroutine call until it returns
+func stepOver() {
+	setPaused(true)
+	if machine.StepOver() {
+		// The interpreter loop pauses again once the call returns
+		setPaused(false)
+		return
+	}
+	reportStep()
+}
+
+// stepOut runs until the current subroutine returns
+func stepOut() {
+	if !machine.StepOut() {
+		fmt.Println("Not in a subroutine, so there's nothing to step out of")
+		return
+	}
+	setPaused(false)
+}
+
+// runToAddress runs until the interpreter reaches an address
+func runToAddress(address uint16) {
+	machine.RunTo(address)
+	setPaused(false)
+}
+
+// toggleBreakpoint adds a breakpoint at an address, or removes the one already there
+func toggleBreakpoint(address uint16) {
+	enabled := !machine.HasBreakpoint(address)
+	machine.SetBreakpoint(address, enabled)
+	if enabled {
+		fmt.Printf("Added breakpoint at 0x%03X\n", address)
+	} else {
+		fmt.Printf("Removed breakpoint at 0x%03X\n", address)
+	}
+}
+
+// setTracing turns logging of every executed instruction to the console on or off
+func setTracing(enabled bool) {
+	if enabled {
+		machine.SetTrace(os.Stdout)
+	} else {
+		machine.SetTrace(nil)
+	}
+}
+
+// outOfRangeAccessesText lists each instruction that has accessed memory out of range, one per line
+func outOfRangeAccessesText() string {
+	accesses := machine.OutOfRangeAccesses()
+	if len(accesses) == 0 {
+		return "No instructions have accessed memory out of range"
+	}
+	lines := make([]string, len(accesses))
+	for i, access := range accesses {
+		lines[i] = access.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseAddress reads a hexadecimal address, with or without a 0x prefix
+func parseAddress(text string) (uint16, error) {
+	text = strings.TrimSpace(strings.ToLower(text))
+	text = strings.TrimPrefix(text, "0x")
+	address, err := strconv.ParseUint(text, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a hexadecimal address", text)
+	}
+	return uint16(address), nil
+}
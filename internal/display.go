@@ -1,9 +1,14 @@
+//go:build !nogui
+
 package internal
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"time"
 
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/lifecycle"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
@@ -12,58 +17,34 @@ const (
 )
 
 var (
-	window            *sdl.Window
-	isDisplaying      bool
-	isDisplayingMutex sync.Mutex
-	closeWindowChan   = make(chan bool)
-
-	display           = make([][]bool, 64)
-	displayMutex      sync.Mutex
+	window *sdl.Window
+	// displayLifecycle owns the window loop, which is running whenever the display is open
+	displayLifecycle  = lifecycle.New()
 	verticalBlankChan = make(chan bool, 1)
 
-	pixelWidth           = 8
-	pixelHeight          = 8
-	horizontalPixelCount = 64
-	verticalPixelCount   = 32
-	windowWidth          = pixelWidth * horizontalPixelCount
-	windowHeight         = pixelHeight * verticalPixelCount
+	windowWidth  = 8 * chip8.DISPLAY_WIDTH
+	windowHeight = 8 * chip8.DISPLAY_HEIGHT
 
-	input      = make([]bool, 16)
-	inputMutex sync.Mutex
-)
-
-func clearDisplay() {
-	displayMutex.Lock()
-	defer displayMutex.Unlock()
-	display = make([][]bool, 64)
-	for i := range display {
-		display[i] = make([]bool, 32)
+	// palette is indexed by the bitmask of planes a pixel is lit on
+	palette = []sdl.Color{
+		{R: 0, G: 0, B: 0, A: 255},       // Black
+		{R: 255, G: 255, B: 255, A: 255}, // White (plane 1)
+		{R: 255, G: 102, B: 0, A: 255},   // Orange (plane 2)
+		{R: 102, G: 34, B: 0, A: 255},    // Brown (both planes)
 	}
-}
+)
 
 func tryOpenDisplay() {
-	isDisplayingMutex.Lock()
-	isDisplaying := isDisplaying
-	isDisplayingMutex.Unlock()
-	if !isDisplaying {
-		go windowLoop()
-	} else {
+	err := displayLifecycle.Start(false, windowLoop)
+	if err != nil {
 		// Display is already open, so highlight it
 		window.Raise()
 	}
 }
 
+// tryCloseDisplay closes the display if it's open, and waits for it to finish
 func tryCloseDisplay() {
-	isDisplayingMutex.Lock()
-	isDisplaying := isDisplaying
-	isDisplayingMutex.Unlock()
-	if isDisplaying {
-		fmt.Println("Closing display")
-		closeWindowChan <- true
-		fmt.Println("Waiting for closeDisplay response")
-		// Await confirmation that the display has closed
-		<-closeWindowChan
-	}
+	displayLifecycle.Stop()
 }
 
 func initialiseWindow() {
@@ -79,12 +60,8 @@ func initialiseWindow() {
 	}
 }
 
-func windowLoop() {
-	fmt.Println("New windowloop")
-	isDisplayingMutex.Lock()
-	isDisplaying = true
-	isDisplayingMutex.Unlock()
-
+// windowLoop shows the display, and draws it every frame until ctx is cancelled or the window is closed
+func windowLoop(ctx context.Context) {
 	window.Show()
 	window.Raise()
 	surface, err := window.GetSurface()
@@ -92,16 +69,21 @@ func windowLoop() {
 		panic(err)
 	}
 
+	frameDuration := time.Second / DISPLAY_REFRESH_RATE
+	nextFrame := time.Now()
+
 	running := true
 	for running {
 		select {
-		case <-closeWindowChan:
+		case <-ctx.Done():
 			running = false
-			fmt.Printf("Received Close Window signal")
 		default:
 			for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 				switch event := event.(type) {
 				case *sdl.KeyboardEvent:
+					if handleHotkeyEvent(event) {
+						break
+					}
 					func() {
 						if event.GetType() == sdl.KEYDOWN {
 							handleKeyEvent(event.Keysym.Sym, true)
@@ -118,91 +100,162 @@ func windowLoop() {
 				break
 			}
 
-			loopTime := sdlLoop(surface)
+			sdlLoop(surface)
 			window.UpdateSurface()
+			updateAudio()
+			// Let the interpreter know it can run the next frame
 			select {
 			case verticalBlankChan <- true:
 			default:
 			}
-			delay := (1000 / DISPLAY_REFRESH_RATE) - loopTime
-			sdl.Delay(delay)
+
+			// Pace frames against a fixed schedule, so that sleep inaccuracy doesn't accumulate
+			nextFrame = nextFrame.Add(frameDuration)
+			if lag := time.Since(nextFrame); lag > frameDuration {
+				// Too far behind to catch up, so drop the missed frames
+				nextFrame = time.Now()
+			}
+			time.Sleep(time.Until(nextFrame))
 		}
 	}
 	// Display has ended, so clean up
 	window.Hide()
-	isDisplayingMutex.Lock()
-	defer isDisplayingMutex.Unlock()
-	isDisplaying = false
-
-	tryStopInterpreter()
-	// Inform any waiting functions that we're wrapped up
-	select {
-	case closeWindowChan <- true:
-	default:
+	if isAudioOpen {
+		sdl.ClearQueuedAudio(audioDevice)
 	}
-	fmt.Println("Displayloop ended")
+	// Nothing can be seen or heard any more, so there's no point in carrying on running
+	tryStopInterpreter()
 }
 
-func sdlLoop(surface *sdl.Surface) uint32 {
+func sdlLoop(surface *sdl.Surface) {
 	// Clear the surface
 	surface.FillRect(nil, 0)
 
-	// Set the pixel's colour and map it to the display's colourspace
-	colour := sdl.Color{R: 255, G: 255, B: 255, A: 255} // White
-	pixel := sdl.MapRGBA(surface.Format, colour.R, colour.G, colour.B, colour.A)
+	// Map each combination of XO-CHIP planes to the display's colourspace
+	pixels := make([]uint32, len(palette))
+	for i, colour := range palette {
+		pixels[i] = sdl.MapRGBA(surface.Format, colour.R, colour.G, colour.B, colour.A)
+	}
 
-	displayMutex.Lock()
-	defer displayMutex.Unlock()
+	display := machine.Display()
+	// Scale the pixels so that both lores and hires displays fill the window
+	pixelWidth := windowWidth / len(display)
+	pixelHeight := windowHeight / len(display[0])
 	for x := range len(display) {
 		for y := range len(display[x]) {
-			if display[x][y] {
+			if display[x][y] != 0 {
 				// Determine the pixels location
 				rect := sdl.Rect{X: int32(x * pixelWidth), Y: int32(y * pixelHeight), W: int32(pixelWidth), H: int32(pixelHeight)}
 				// Draw a rectangle
-				surface.FillRect(&rect, pixel)
+				surface.FillRect(&rect, pixels[display[x][y]])
 			}
 		}
 	}
+}
+
+// handleHotkeyEvent applies any emulator hotkeys, and reports whether the key was one of them
+func handleHotkeyEvent(event *sdl.KeyboardEvent) bool {
+	isPressed := event.GetType() == sdl.KEYDOWN
+	// Toggles should only fire once per press, not on every key repeat
+	isNewPress := isPressed && event.Repeat == 0
 
-	return 0
+	switch event.Keysym.Sym {
+	case sdl.K_TAB:
+		// Fast forward while held
+		setTurbo(isPressed)
+	case sdl.K_BACKSPACE:
+		// Rewind while held
+		setRewinding(isPressed)
+	case sdl.K_p:
+		if isNewPress {
+			togglePause()
+		}
+	case sdl.K_n:
+		if isPressed {
+			advanceFrame()
+		}
+	case sdl.K_m:
+		if isNewPress {
+			toggleSlowMotion()
+		}
+	case sdl.K_EQUALS:
+		if isPressed {
+			stepSpeedPreset(1)
+		}
+	case sdl.K_MINUS:
+		if isPressed {
+			stepSpeedPreset(-1)
+		}
+	case sdl.K_F9:
+		if isNewPress {
+			toggleBreakpoint(machine.PC())
+		}
+	case sdl.K_F10:
+		if isPressed {
+			stepOver()
+		}
+	case sdl.K_F11:
+		if isPressed {
+			if event.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+				stepOut()
+			} else {
+				stepInstruction()
+			}
+		}
+	case sdl.K_F1, sdl.K_F2, sdl.K_F3, sdl.K_F4, sdl.K_F5, sdl.K_F6, sdl.K_F7, sdl.K_F8:
+		// F1-F8 load from the matching save state slot, or save to it while Shift is held
+		if isNewPress {
+			slot := int(event.Keysym.Sym-sdl.K_F1) + 1
+			var err error
+			if event.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+				err = saveStateSlot(slot)
+			} else {
+				err = loadStateSlot(slot)
+			}
+			if err != nil {
+				fmt.Printf("Save state slot %d failed: %v\n", slot, err)
+			}
+		}
+	default:
+		return false
+	}
+	return true
 }
 
 func handleKeyEvent(keyCode sdl.Keycode, isPressed bool) {
-	inputMutex.Lock()
-	defer inputMutex.Unlock()
 	// TODO - Handle input a bit more sanely, instead of this big switch
 	switch keyCode {
 	case sdl.GetKeyFromName("1"):
-		input[1] = isPressed
+		machine.SetKey(1, isPressed)
 	case sdl.GetKeyFromName("2"):
-		input[2] = isPressed
+		machine.SetKey(2, isPressed)
 	case sdl.GetKeyFromName("3"):
-		input[3] = isPressed
+		machine.SetKey(3, isPressed)
 	case sdl.GetKeyFromName("4"):
-		input[12] = isPressed
+		machine.SetKey(12, isPressed)
 	case sdl.GetKeyFromName("Q"):
-		input[4] = isPressed
+		machine.SetKey(4, isPressed)
 	case sdl.GetKeyFromName("W"):
-		input[5] = isPressed
+		machine.SetKey(5, isPressed)
 	case sdl.GetKeyFromName("E"):
-		input[6] = isPressed
+		machine.SetKey(6, isPressed)
 	case sdl.GetKeyFromName("R"):
-		input[13] = isPressed
+		machine.SetKey(13, isPressed)
 	case sdl.GetKeyFromName("A"):
-		input[7] = isPressed
+		machine.SetKey(7, isPressed)
 	case sdl.GetKeyFromName("S"):
-		input[8] = isPressed
+		machine.SetKey(8, isPressed)
 	case sdl.GetKeyFromName("D"):
-		input[9] = isPressed
+		machine.SetKey(9, isPressed)
 	case sdl.GetKeyFromName("F"):
-		input[14] = isPressed
+		machine.SetKey(14, isPressed)
 	case sdl.GetKeyFromName("Z"):
-		input[10] = isPressed
+		machine.SetKey(10, isPressed)
 	case sdl.GetKeyFromName("X"):
-		input[0] = isPressed
+		machine.SetKey(0, isPressed)
 	case sdl.GetKeyFromName("C"):
-		input[11] = isPressed
+		machine.SetKey(11, isPressed)
 	case sdl.GetKeyFromName("V"):
-		input[15] = isPressed
+		machine.SetKey(15, isPressed)
 	}
 }
@@ -0,0 +1,48 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+// faultDialog is the open fault dialog, so that only one is ever shown
+var faultDialog *dialog.CustomDialog
+
+// showFaultDialog shows where the interpreter faulted, and asks whether to skip the instruction and continue,
+// pause in the debugger, or reset the ROM. Must be called on the UI thread
+func showFaultDialog(fault *chip8.Fault, fyneWindow fyne.Window) {
+	if faultDialog != nil {
+		return
+	}
+	details := widget.NewForm(
+		widget.NewFormItem("Error", widget.NewLabel(fault.Err.Error())),
+		widget.NewFormItem("PC", widget.NewLabel(fmt.Sprintf("0x%03X", fault.PC))),
+		widget.NewFormItem("Opcode", widget.NewLabel(fmt.Sprintf("%04X  %s", fault.Opcode, chip8.Decode(fault.Opcode)))),
+	)
+	content := container.NewVBox(
+		details,
+		widget.NewLabel("The interpreter has stopped at the instruction, with its state kept for inspection."),
+	)
+
+	faultDialog = dialog.NewCustomWithoutButtons(fault.Kind.String(), content, fyneWindow)
+	choose := func(action func()) func() {
+		return func() {
+			faultDialog.Hide()
+			action()
+		}
+	}
+	faultDialog.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Ignore And Continue", choose(ignoreFault)),
+		widget.NewButton("Pause In Debugger", choose(debugFault)),
+		widget.NewButton("Reset", choose(func() { go resetAfterFault() })),
+	})
+	faultDialog.SetOnClosed(func() { faultDialog = nil })
+	faultDialog.Show()
+}
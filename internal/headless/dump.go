@@ -0,0 +1,81 @@
+package headless
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+// PIXEL_CHARS are the characters used for each pixel value in a text dump of the display,
+// from both planes off to both planes on
+const PIXEL_CHARS = ".#+%"
+
+// pixelColours are the colours used for each pixel value in an image of the display
+var pixelColours = []color.Color{
+	color.Gray{Y: 0x00},
+	color.Gray{Y: 0xFF},
+	color.RGBA{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF},
+	color.RGBA{R: 0x55, G: 0x55, B: 0x55, A: 0xFF},
+}
+
+// DisplayText draws the display as text, with one line per row of pixels
+func DisplayText(display [][]uint8) string {
+	if len(display) == 0 {
+		return ""
+	}
+	var text strings.Builder
+	for y := range display[0] {
+		for x := range display {
+			text.WriteByte(PIXEL_CHARS[display[x][y]&chip8.PLANE_BOTH])
+		}
+		text.WriteByte('\n')
+	}
+	return text.String()
+}
+
+// WriteDisplayPNG draws the display as a PNG image, with each pixel scaled up into a square
+func WriteDisplayPNG(w io.Writer, display [][]uint8, scale int) error {
+	width, height := len(display), 0
+	if width > 0 {
+		height = len(display[0])
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+	for x := range img.Bounds().Dx() {
+		for y := range img.Bounds().Dy() {
+			img.Set(x, y, pixelColours[display[x/scale][y/scale]&chip8.PLANE_BOTH])
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// RegistersText lists the registers, timers, and stack, one per line
+func RegistersText(registers chip8.Registers) string {
+	var text strings.Builder
+	for i, value := range registers.V {
+		fmt.Fprintf(&text, "V%X=%02X\n", i, value)
+	}
+	fmt.Fprintf(&text, "I=%04X\nPC=%04X\nDT=%02X\nST=%02X\n", registers.I, registers.PC, registers.DelayTimer, registers.SoundTimer)
+	for i, address := range registers.Stack {
+		fmt.Fprintf(&text, "STACK%d=%04X\n", i, address)
+	}
+	fmt.Fprintf(&text, "NEXT=%04X %s\n", registers.Next.Opcode, registers.Next)
+	return text.String()
+}
+
+// MemoryText lists memory as hex, 16 bytes per line
+func MemoryText(memory []byte) string {
+	var text strings.Builder
+	for start := 0; start < len(memory); start += 16 {
+		fmt.Fprintf(&text, "%04X ", start)
+		for _, b := range memory[start:min(start+16, len(memory))] {
+			fmt.Fprintf(&text, " %02X", b)
+		}
+		text.WriteByte('\n')
+	}
+	return text.String()
+}
@@ -0,0 +1,131 @@
+package headless
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/roms"
+)
+
+// GOLDEN_SEED is the random seed for every golden run, so that ROMs using CXNN draw the same screen each time
+const GOLDEN_SEED = 1
+
+// GOLDEN_ROMS_DIR is where the Timendus test ROMs are expected, alongside the repository as with the Load ROM
+// menu, unless CHIP8_ROMS_DIR is set
+const GOLDEN_ROMS_DIR = "../../../chip8-roms"
+
+var update = flag.Bool("update", false, "rewrite the golden screens from the current output")
+
+// goldenModes are the modes every test ROM is run in, along with the value written to 0x1FF to skip the test's
+// platform menu. The SUPER-CHIP quirk presets match the "modern" SUPER-CHIP option
+var goldenModes = []struct {
+	name     string
+	mode     chip8.InterpreterMode
+	platform byte
+}{
+	{"chip8", chip8.MODE_CHIP8, 1},
+	{"superchip", chip8.MODE_SUPERCHIP, 2},
+	{"xochip", chip8.MODE_XOCHIP, 3},
+}
+
+// goldenRoms are the Timendus test ROMs from the library, with how many frames each needs to finish drawing
+var goldenRoms = []struct {
+	name    string
+	library string
+	frames  int
+}{
+	{"chip8-logo", "testsuite1", 60},
+	{"ibm-logo", "testsuite2", 60},
+	{"corax+", "testsuite3", 120},
+	{"flags", "testsuite4", 120},
+	{"quirks", "testsuite5", 600},
+	{"keypad", "testsuite6", 60},
+}
+
+// TestGoldenScreens runs each test ROM in each mode, and compares the final display to the golden screen
+// in testdata/golden. Run with -update to accept the current output as the new golden screens, but only once
+// each test has been checked to pass in that mode
+func TestGoldenScreens(t *testing.T) {
+	if os.Getenv("CHIP8_ROMS_DIR") == "" {
+		t.Setenv("CHIP8_ROMS_DIR", GOLDEN_ROMS_DIR)
+	}
+
+	for _, rom := range goldenRoms {
+		for _, mode := range goldenModes {
+			t.Run(rom.name+"/"+mode.name, func(t *testing.T) {
+				libraryRom, ok := roms.Find(rom.library)
+				if !ok {
+					t.Fatalf("%s isn't in the ROM library, copy the Timendus test ROMs into %s", rom.library, roms.Dir())
+				}
+				data, err := libraryRom.Data()
+				if err != nil {
+					t.Fatal(err)
+				}
+				m, err := NewMachine(bytes.NewReader(data), mode.mode, chip8.DefaultQuirks(mode.mode), GOLDEN_SEED)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = m.WriteMemory(0x1FF, []byte{mode.platform})
+				if err != nil {
+					t.Fatal(err)
+				}
+				Run(m, rom.frames, 10)
+				actual := DisplayText(m.Display())
+
+				goldenPath := filepath.Join("testdata", "golden", fmt.Sprintf("%s-%s.txt", rom.name, mode.name))
+				if *update {
+					err := os.MkdirAll(filepath.Dir(goldenPath), 0755)
+					if err != nil {
+						t.Fatal(err)
+					}
+					err = os.WriteFile(goldenPath, []byte(actual), 0644)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				expected, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("no golden screen, run the tests with -update to create one once the ROM passes: %v", err)
+				}
+				if actual != string(expected) {
+					t.Errorf("display doesn't match %s\n%s", goldenPath, displayDiff(string(expected), actual))
+				}
+			})
+		}
+	}
+}
+
+// displayDiff shows the expected and actual displays side by side, with X marking each pixel that differs
+func displayDiff(expected string, actual string) string {
+	expectedRows := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	actualRows := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	var diff strings.Builder
+	diff.WriteString("expected | actual | differences\n")
+	for y := range max(len(expectedRows), len(actualRows)) {
+		var expectedRow, actualRow string
+		if y < len(expectedRows) {
+			expectedRow = expectedRows[y]
+		}
+		if y < len(actualRows) {
+			actualRow = actualRows[y]
+		}
+		differences := make([]byte, max(len(expectedRow), len(actualRow)))
+		for x := range differences {
+			differences[x] = ' '
+			if x >= len(expectedRow) || x >= len(actualRow) || expectedRow[x] != actualRow[x] {
+				differences[x] = 'X'
+			}
+		}
+		fmt.Fprintf(&diff, "%s | %s | %s\n", expectedRow, actualRow, differences)
+	}
+	return diff.String()
+}
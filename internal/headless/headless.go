@@ -0,0 +1,59 @@
+// Package headless runs ROMs without any windows, sound, or input, for use in scripts and tests
+package headless
+
+import (
+	"io"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/sound"
+)
+
+const (
+	// SAMPLES_PER_FRAME is how much of the buzzer's output is recorded for each 60Hz frame
+	SAMPLES_PER_FRAME = sound.SAMPLE_RATE / 60
+	// INSTRUCTIONS_PER_FRAME is the speed runs default to, 600 instructions a second as in the UI
+	INSTRUCTIONS_PER_FRAME = 10
+)
+
+// NewMachine creates a Machine in the given mode and quirks, with a ROM loaded and ready to run.
+// The random number generator starts from seed, so that a run can be repeated exactly
+func NewMachine(rom io.Reader, mode chip8.InterpreterMode, quirks chip8.Quirks, seed uint64) (*chip8.Machine, error) {
+	m := chip8.New(mode)
+	m.SetQuirks(quirks)
+	m.SetSeed(seed)
+	err := m.LoadRom(rom)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run executes up to the given number of frames, stopping early if the ROM exits or an instruction faults.
+// Returns the number of frames that were run
+func Run(m *chip8.Machine, frames int, instructionsPerFrame int) int {
+	return run(m, frames, instructionsPerFrame, func() {})
+}
+
+// RunWithAudio runs like Run, and also returns the buzzer's output over the frames that were run
+func RunWithAudio(m *chip8.Machine, frames int, instructionsPerFrame int, buzzer *sound.Buzzer) (int, []float32) {
+	var samples []float32
+	framesRun := run(m, frames, instructionsPerFrame, func() {
+		samples = append(samples, buzzer.Samples(SAMPLES_PER_FRAME, sound.StateOf(m))...)
+	})
+	return framesRun, samples
+}
+
+// run executes frames as Run does, calling afterFrame once each frame has run
+func run(m *chip8.Machine, frames int, instructionsPerFrame int, afterFrame func()) int {
+	for frame := range frames {
+		if m.Halted() {
+			return frame
+		}
+		reason := m.RunFrame(instructionsPerFrame)
+		afterFrame()
+		if reason == chip8.STOP_FAULT {
+			return frame + 1
+		}
+	}
+	return frames
+}
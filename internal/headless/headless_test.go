@@ -0,0 +1,33 @@
+package headless
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/sound"
+)
+
+func TestRunWithAudio(t *testing.T) {
+	// Sets the sound timer to 10 frames, then loops forever
+	rom := []byte{0x60, 0x0A, 0xF0, 0x18, 0x12, 0x04}
+	m, err := NewMachine(bytes.NewReader(rom), chip8.MODE_CHIP8, chip8.DefaultQuirks(chip8.MODE_CHIP8), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	framesRun, samples := RunWithAudio(m, 20, 10, sound.NewBuzzer())
+	if framesRun != 20 || len(samples) != 20*SAMPLES_PER_FRAME {
+		t.Fatalf("ran %d frames with %d samples, expected 20 frames of %d samples", framesRun, len(samples), SAMPLES_PER_FRAME)
+	}
+
+	// The timer is set during the first frame, and ticks down at the start of each frame after it
+	for frame := range 20 {
+		audible := false
+		for _, sample := range samples[frame*SAMPLES_PER_FRAME : (frame+1)*SAMPLES_PER_FRAME] {
+			audible = audible || sample != 0
+		}
+		if expected := frame < 10; audible != expected {
+			t.Errorf("frame %d audible = %v, expected %v", frame, audible, expected)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/lifecycle"
+)
+
+const INSPECTOR_REFRESH_RATE = 15
+
+// inspectorField is a single editable value shown in the inspector, such as a register or timer
+type inspectorField struct {
+	name  *widget.Label
+	value *widget.Entry
+	// format turns the field's value into the text shown in the entry
+	format func(chip8.Registers) string
+	// parse writes text typed into the entry back into the registers
+	parse func(*chip8.Registers, string) error
+}
+
+// newInspectorPanel builds the register, timer, and stack inspector for the controller window.
+// Values refresh live while running, can be edited while paused, and are highlighted when they change.
+// It stops refreshing once closed is closed
+func newInspectorPanel(closed chan bool) fyne.CanvasObject {
+	var fields []*inspectorField
+	var refresh func()
+
+	addField := func(name string, format func(chip8.Registers) string, parse func(*chip8.Registers, string) error) {
+		field := &inspectorField{
+			name:   widget.NewLabel(name),
+			value:  widget.NewEntry(),
+			format: format,
+			parse:  parse,
+		}
+		field.value.OnSubmitted = func(text string) {
+			registers := machine.Registers()
+			err := field.parse(&registers, text)
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				machine.SetRegisters(registers)
+			}
+			refresh()
+		}
+		fields = append(fields, field)
+	}
+
+	for i := range 16 {
+		addField(fmt.Sprintf("V%X", i),
+			func(registers chip8.Registers) string { return fmt.Sprintf("%02X", registers.V[i]) },
+			func(registers *chip8.Registers, text string) error {
+				value, err := parseHexValue(text, 8)
+				registers.V[i] = uint8(value)
+				return err
+			})
+	}
+	addField("I",
+		func(registers chip8.Registers) string { return fmt.Sprintf("%04X", registers.I) },
+		func(registers *chip8.Registers, text string) error {
+			value, err := parseHexValue(text, 16)
+			registers.I = uint16(value)
+			return err
+		})
+	addField("PC",
+		func(registers chip8.Registers) string { return fmt.Sprintf("%04X", registers.PC) },
+		func(registers *chip8.Registers, text string) error {
+			value, err := parseHexValue(text, 16)
+			registers.PC = uint16(value)
+			return err
+		})
+	addField("DT",
+		func(registers chip8.Registers) string { return fmt.Sprintf("%02X", registers.DelayTimer) },
+		func(registers *chip8.Registers, text string) error {
+			value, err := parseHexValue(text, 8)
+			registers.DelayTimer = uint8(value)
+			return err
+		})
+	addField("ST",
+		func(registers chip8.Registers) string { return fmt.Sprintf("%02X", registers.SoundTimer) },
+		func(registers *chip8.Registers, text string) error {
+			value, err := parseHexValue(text, 8)
+			registers.SoundTimer = uint8(value)
+			return err
+		})
+
+	opcodeLabel := widget.NewLabel("")
+	opcodeLabel.TextStyle.Monospace = true
+	stackLabel := widget.NewLabel("")
+	stackLabel.TextStyle.Monospace = true
+	stateLabel := widget.NewLabel(interpreterLifecycle.State().String())
+	seedLabel := widget.NewLabel("")
+	unsubscribe := interpreterLifecycle.Subscribe(func(event lifecycle.Event) {
+		fyne.Do(func() { stateLabel.SetText(event.To.String()) })
+	})
+
+	registerGrid := container.NewGridWithColumns(8)
+	for _, field := range fields {
+		field.value.TextStyle.Monospace = true
+		registerGrid.Add(field.name)
+		registerGrid.Add(field.value)
+	}
+
+	// lastRegisters is the snapshot currently on screen, so that only values which change get highlighted
+	var lastRegisters *chip8.Registers
+	refresh = func() {
+		registers := machine.Registers()
+		editable := getPaused()
+		seedLabel.SetText(fmt.Sprintf("Seed %d", machine.Seed()))
+
+		for _, field := range fields {
+			if editable {
+				field.value.Enable()
+			} else {
+				field.value.Disable()
+			}
+		}
+		// Leave the entries alone until something changes, so that a value being typed in isn't overwritten
+		if lastRegisters != nil && registersEqual(registers, *lastRegisters) {
+			return
+		}
+
+		// Highlight whatever has changed since the values were last updated, such as after a step
+		for _, field := range fields {
+			text := field.format(registers)
+			highlight := lastRegisters != nil && text != field.format(*lastRegisters)
+			field.name.Importance = widget.MediumImportance
+			if highlight {
+				field.name.Importance = widget.HighImportance
+			}
+			field.name.Refresh()
+			field.value.SetText(text)
+		}
+		opcodeLabel.SetText(fmt.Sprintf("%04X  %s", registers.Next.Opcode, registers.Next))
+		stackLabel.SetText(stackText(registers.Stack))
+		lastRegisters = &registers
+	}
+	refresh()
+
+	go func() {
+		ticker := time.NewTicker(time.Second / INSPECTOR_REFRESH_RATE)
+		defer ticker.Stop()
+		defer unsubscribe()
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+				fyne.Do(refresh)
+			}
+		}
+	}()
+
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabelWithStyle("Interpreter", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), stateLabel, seedLabel),
+		widget.NewLabelWithStyle("Registers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		registerGrid,
+		widget.NewLabelWithStyle("Next Instruction", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		opcodeLabel,
+		widget.NewLabelWithStyle("Stack", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		stackLabel,
+	)
+}
+
+// registersEqual reports whether two register snapshots hold the same values
+func registersEqual(a, b chip8.Registers) bool {
+	return a.V == b.V && a.I == b.I && a.PC == b.PC &&
+		a.DelayTimer == b.DelayTimer && a.SoundTimer == b.SoundTimer &&
+		a.Next == b.Next && slices.Equal(a.Stack, b.Stack)
+}
+
+// stackText lists the return addresses on the stack, most recent call first
+func stackText(stack []uint16) string {
+	if len(stack) == 0 {
+		return "(empty)"
+	}
+	lines := make([]string, len(stack))
+	for i, address := range stack {
+		lines[len(stack)-1-i] = fmt.Sprintf("%2d: %04X", i, address)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseHexValue reads a hexadecimal value that fits in the given number of bits, with or without a 0x prefix
+func parseHexValue(text string, bits int) (uint64, error) {
+	text = strings.TrimSpace(strings.ToLower(text))
+	text = strings.TrimPrefix(text, "0x")
+	value, err := strconv.ParseUint(text, 16, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a %d-bit hexadecimal value", text, bits)
+	}
+	return value, nil
+}
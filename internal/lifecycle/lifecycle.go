@@ -0,0 +1,224 @@
+// Package lifecycle tracks whether an interpreter is stopped, loading, running, paused, or faulted. It owns the
+// goroutine that runs the interpreter, stopping it through a context, and reports every change of state to
+// its subscribers
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+type State int
+
+const (
+	// STATE_STOPPED means no loop is running
+	STATE_STOPPED State = iota
+	// STATE_LOADING means a ROM is being loaded, and the loop will be started once it's ready
+	STATE_LOADING
+	// STATE_RUNNING means the loop is running
+	STATE_RUNNING
+	// STATE_PAUSED means the loop is running, but waiting to be resumed or stepped
+	STATE_PAUSED
+	// STATE_FAULTED means the loop is running, but stopped on an instruction that couldn't be executed
+	STATE_FAULTED
+)
+
+var ErrInvalidTransition = errors.New("invalid lifecycle transition")
+
+func (state State) String() string {
+	switch state {
+	case STATE_STOPPED:
+		return "Stopped"
+	case STATE_LOADING:
+		return "Loading"
+	case STATE_RUNNING:
+		return "Running"
+	case STATE_PAUSED:
+		return "Paused"
+	case STATE_FAULTED:
+		return "Faulted"
+	}
+	return fmt.Sprintf("State(%d)", int(state))
+}
+
+// Event is a change from one state to another
+type Event struct {
+	From State
+	To   State
+}
+
+type subscriber struct {
+	id     int
+	handle func(Event)
+}
+
+// Lifecycle is the state of one interpreter loop. The zero value is stopped and ready to use
+type Lifecycle struct {
+	mu    sync.Mutex
+	state State
+	// cancel stops the running loop, and done is closed once it has returned. Both are nil while no loop is running
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	subscribers    []subscriber
+	nextSubscriber int
+	// pending holds events waiting to be delivered, while notifying is set by whichever goroutine is delivering them
+	pending   []Event
+	notifying bool
+}
+
+// New creates a stopped Lifecycle
+func New() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// State returns the current state
+func (l *Lifecycle) State() State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state
+}
+
+// Subscribe calls handle with every change of state from now on, until the returned function is called.
+// Events are delivered one at a time, in the order the changes happened, from whichever goroutine made the change.
+// handle must not block, or change the state itself
+func (l *Lifecycle) Subscribe(handle func(Event)) (unsubscribe func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id := l.nextSubscriber
+	l.nextSubscriber++
+	l.subscribers = append(l.subscribers, subscriber{id: id, handle: handle})
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.subscribers = slices.DeleteFunc(l.subscribers, func(s subscriber) bool { return s.id == id })
+	}
+}
+
+// Load moves a stopped Lifecycle to loading, while a ROM is loaded ready for Start
+func (l *Lifecycle) Load() error {
+	defer l.notify()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.transition(STATE_LOADING, STATE_STOPPED)
+}
+
+// Start runs loop in a new goroutine, either running or paused. The loop's context is cancelled by Stop, and the
+// loop should return promptly once it is. If the loop returns by itself, the Lifecycle is stopped
+func (l *Lifecycle) Start(paused bool, loop func(ctx context.Context)) error {
+	defer l.notify()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := STATE_RUNNING
+	if paused {
+		state = STATE_PAUSED
+	}
+	err := l.transition(state, STATE_STOPPED, STATE_LOADING)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	l.cancel = cancel
+	l.done = done
+
+	go func() {
+		defer close(done)
+		defer l.notify()
+		defer cancel()
+		loop(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		// Stop may have already moved on, and a new loop may even have been started since
+		if l.done == done {
+			l.cancel = nil
+			l.done = nil
+			l.transition(STATE_STOPPED)
+		}
+	}()
+	return nil
+}
+
+// Stop cancels the running loop, and waits for it to return. It's safe to call at any time, including when
+// nothing is running, or the loop has already returned by itself, but not from within the loop
+func (l *Lifecycle) Stop() {
+	defer l.notify()
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.cancel = nil
+	l.done = nil
+	if l.state != STATE_STOPPED {
+		l.transition(STATE_STOPPED)
+	}
+	l.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// SetPaused moves a running loop between running and paused, which also clears a fault.
+// Does nothing unless a loop is running
+func (l *Lifecycle) SetPaused(paused bool) {
+	defer l.notify()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := STATE_RUNNING
+	if paused {
+		state = STATE_PAUSED
+	}
+	if l.state != state {
+		l.transition(state, STATE_RUNNING, STATE_PAUSED, STATE_FAULTED)
+	}
+}
+
+// Fault moves a running loop to faulted, until it's paused or resumed again. Does nothing unless a loop is running
+func (l *Lifecycle) Fault() {
+	defer l.notify()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.state != STATE_FAULTED {
+		l.transition(STATE_FAULTED, STATE_RUNNING, STATE_PAUSED)
+	}
+}
+
+// transition moves to a new state, as long as the current state is one of from, or from is empty.
+// The change is queued up for notify to deliver. The caller must hold l.mu
+func (l *Lifecycle) transition(to State, from ...State) error {
+	if len(from) > 0 && !slices.Contains(from, l.state) {
+		return fmt.Errorf("%w from %v to %v", ErrInvalidTransition, l.state, to)
+	}
+	l.pending = append(l.pending, Event{From: l.state, To: to})
+	l.state = to
+	return nil
+}
+
+// notify delivers any pending events to the subscribers, unless another goroutine is already delivering them.
+// The caller must not hold l.mu
+func (l *Lifecycle) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.notifying {
+		return
+	}
+	l.notifying = true
+	for len(l.pending) > 0 {
+		event := l.pending[0]
+		l.pending = l.pending[1:]
+		subscribers := slices.Clone(l.subscribers)
+
+		l.mu.Unlock()
+		for _, s := range subscribers {
+			s.handle(event)
+		}
+		l.mu.Lock()
+	}
+	l.notifying = false
+}
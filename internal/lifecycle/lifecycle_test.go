@@ -0,0 +1,160 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordEvents subscribes to a Lifecycle, and returns a function listing the events seen so far
+func recordEvents(l *Lifecycle) func() []Event {
+	var mu sync.Mutex
+	var events []Event
+	l.Subscribe(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return func() []Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(events)
+	}
+}
+
+// waitForState fails the test if the Lifecycle doesn't reach a state within a second
+func waitForState(t *testing.T, l *Lifecycle, state State) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for l.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %v, expected %v", l.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForCancel is a loop that runs until it's stopped
+func waitForCancel(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func TestLifecycle(t *testing.T) {
+	l := New()
+	events := recordEvents(l)
+
+	if err := l.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Start(false, waitForCancel); err != nil {
+		t.Fatal(err)
+	}
+	l.SetPaused(true)
+	l.Fault()
+	l.SetPaused(false)
+	l.Stop()
+
+	expected := []Event{
+		{STATE_STOPPED, STATE_LOADING},
+		{STATE_LOADING, STATE_RUNNING},
+		{STATE_RUNNING, STATE_PAUSED},
+		{STATE_PAUSED, STATE_FAULTED},
+		{STATE_FAULTED, STATE_RUNNING},
+		{STATE_RUNNING, STATE_STOPPED},
+	}
+	if !slices.Equal(events(), expected) {
+		t.Errorf("events = %v, expected %v", events(), expected)
+	}
+}
+
+func TestInvalidTransitions(t *testing.T) {
+	l := New()
+	events := recordEvents(l)
+
+	// Pausing and faulting only apply to a running loop
+	l.Fault()
+	l.SetPaused(true)
+	if l.State() != STATE_STOPPED {
+		t.Errorf("state = %v after faulting and pausing while stopped, expected Stopped", l.State())
+	}
+
+	if err := l.Start(true, waitForCancel); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Stop()
+	if err := l.Start(false, waitForCancel); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Start() while running = %v, expected ErrInvalidTransition", err)
+	}
+	if err := l.Load(); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Load() while running = %v, expected ErrInvalidTransition", err)
+	}
+	if len(events()) != 1 || l.State() != STATE_PAUSED {
+		t.Errorf("events = %v, expected only the start", events())
+	}
+}
+
+func TestStopAfterLoopReturns(t *testing.T) {
+	l := New()
+	events := recordEvents(l)
+	if err := l.Start(false, func(ctx context.Context) {}); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, l, STATE_STOPPED)
+
+	// There's no loop left to stop, so this mustn't wait for one
+	stopped := make(chan struct{})
+	go func() {
+		l.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked after the loop had already returned")
+	}
+	if len(events()) != 2 {
+		t.Errorf("events = %v, expected a single stop", events())
+	}
+}
+
+func TestStopWaitsForLoop(t *testing.T) {
+	l := New()
+	returned := false
+	err := l.Start(false, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		returned = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Stop()
+	if !returned {
+		t.Error("Stop() returned before the loop did")
+	}
+	if l.State() != STATE_STOPPED {
+		t.Errorf("state = %v, expected Stopped", l.State())
+	}
+
+	// Stopping again does nothing, and a new loop can be started
+	l.Stop()
+	if err := l.Start(false, waitForCancel); err != nil {
+		t.Fatal(err)
+	}
+	l.Stop()
+}
+
+func TestUnsubscribe(t *testing.T) {
+	l := New()
+	count := 0
+	unsubscribe := l.Subscribe(func(Event) { count++ })
+	l.Load()
+	unsubscribe()
+	l.Stop()
+	if count != 1 {
+		t.Errorf("handler called %d times, expected once before unsubscribing", count)
+	}
+}
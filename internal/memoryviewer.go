@@ -0,0 +1,248 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+const (
+	MEMORY_VIEWER_BYTES_PER_ROW = 16
+	MEMORY_VIEWER_REFRESH_RATE  = 10
+)
+
+// Colours used to pick out interesting areas of memory
+var (
+	memoryFontColour   = color.NRGBA{R: 0x30, G: 0x50, B: 0x90, A: 0xFF}
+	memoryRomColour    = color.NRGBA{R: 0x30, G: 0x70, B: 0x40, A: 0xFF}
+	memoryPCColour     = color.NRGBA{R: 0xC0, G: 0x30, B: 0x30, A: 0xFF}
+	memoryIndexColour  = color.NRGBA{R: 0xA0, G: 0x50, B: 0xC0, A: 0xFF}
+	memoryCursorColour = color.NRGBA{R: 0xC0, G: 0xA0, B: 0x20, A: 0xFF}
+)
+
+// memoryViewerWindow is the open memory viewer, so that only one is ever shown
+var memoryViewerWindow fyne.Window
+
+// showMemoryViewer opens the memory viewer window, or brings it to the front if it's already open
+func showMemoryViewer() {
+	if memoryViewerWindow != nil {
+		memoryViewerWindow.RequestFocus()
+		return
+	}
+	closed := make(chan bool)
+	memoryViewerWindow = fyne.CurrentApp().NewWindow("Memory")
+	memoryViewerWindow.SetContent(newMemoryViewer(memoryViewerWindow, closed))
+	memoryViewerWindow.Resize(fyne.NewSize(640, 560))
+	memoryViewerWindow.SetOnClosed(func() {
+		memoryViewerWindow = nil
+		close(closed)
+	})
+	memoryViewerWindow.Show()
+}
+
+// newMemoryViewer builds a scrollable hex and ASCII view of memory, which can follow PC or I,
+// jump to an address, search for a byte pattern, and edit memory while paused. It stops refreshing once closed is closed
+func newMemoryViewer(fyneWindow fyne.Window, closed chan bool) fyne.CanvasObject {
+	memory := machine.Memory()
+	registers := machine.Registers()
+	romSize := machine.RomSize()
+	// The cursor marks the last address jumped to, searched for, or selected, and is where edits are written
+	cursor, cursorLength := 0, 1
+
+	list := widget.NewList(
+		func() int { return (len(memory) + MEMORY_VIEWER_BYTES_PER_ROW - 1) / MEMORY_VIEWER_BYTES_PER_ROW },
+		func() fyne.CanvasObject {
+			grid := widget.NewTextGrid()
+			grid.SetText(strings.Repeat(" ", 6+3*MEMORY_VIEWER_BYTES_PER_ROW+1+MEMORY_VIEWER_BYTES_PER_ROW))
+			return grid
+		},
+		func(row widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.TextGrid).SetRow(0, memoryViewerRow(memory, row*MEMORY_VIEWER_BYTES_PER_ROW, func(address int) color.Color {
+				switch {
+				case address >= cursor && address < cursor+cursorLength:
+					return memoryCursorColour
+				case address == int(registers.PC) || address == int(registers.PC)+1:
+					return memoryPCColour
+				case address == int(registers.I):
+					return memoryIndexColour
+				case address >= chip8.MEM_FONT_DATA_START && address < chip8.MEM_FONT_DATA_END:
+					return memoryFontColour
+				case address >= chip8.MEM_ROM_START && address < chip8.MEM_ROM_START+romSize:
+					return memoryRomColour
+				}
+				return nil
+			}))
+		},
+	)
+
+	moveCursor := func(address int, length int) {
+		cursor, cursorLength = address, length
+		list.ScrollTo(address / MEMORY_VIEWER_BYTES_PER_ROW)
+		list.Refresh()
+	}
+	list.OnSelected = func(row widget.ListItemID) {
+		moveCursor(row*MEMORY_VIEWER_BYTES_PER_ROW, 1)
+		list.UnselectAll()
+	}
+
+	followSelect := widget.NewSelect([]string{"None", "PC", "I"}, nil)
+	followSelect.SetSelected("PC")
+
+	addressEntry := widget.NewEntry()
+	addressEntry.SetPlaceHolder("Address")
+	jumpButton := widget.NewButton("Jump", func() {
+		address, err := parseAddress(addressEntry.Text)
+		if err != nil || int(address) >= len(memory) {
+			dialog.ShowError(fmt.Errorf("%q is not an address in memory", addressEntry.Text), fyneWindow)
+			return
+		}
+		followSelect.SetSelected("None")
+		moveCursor(int(address), 1)
+	})
+	addressEntry.OnSubmitted = func(string) { jumpButton.OnTapped() }
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Bytes, e.g. A2 2A")
+	searchButton := widget.NewButton("Find Next", func() {
+		pattern, err := parseHexBytes(searchEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, fyneWindow)
+			return
+		}
+		// Search onwards from just past the cursor, wrapping back around to the start of memory
+		start := min(cursor+1, len(memory))
+		index := bytes.Index(memory[start:], pattern)
+		if index >= 0 {
+			index += start
+		} else {
+			index = bytes.Index(memory, pattern)
+		}
+		if index < 0 {
+			dialog.ShowInformation("Find", fmt.Sprintf("%X was not found in memory", pattern), fyneWindow)
+			return
+		}
+		followSelect.SetSelected("None")
+		moveCursor(index, len(pattern))
+	})
+	searchEntry.OnSubmitted = func(string) { searchButton.OnTapped() }
+
+	cursorLabel := widget.NewLabel("")
+	valueEntry := widget.NewEntry()
+	valueEntry.SetPlaceHolder("Bytes to write at the cursor")
+	writeButton := widget.NewButton("Write", func() {
+		data, err := parseHexBytes(valueEntry.Text)
+		if err == nil {
+			err = machine.WriteMemory(uint16(cursor), data)
+		}
+		if err != nil {
+			dialog.ShowError(err, fyneWindow)
+			return
+		}
+		valueEntry.SetText("")
+	})
+	valueEntry.OnSubmitted = func(string) {
+		if !writeButton.Disabled() {
+			writeButton.OnTapped()
+		}
+	}
+
+	refresh := func() {
+		memory = machine.Memory()
+		registers = machine.Registers()
+		romSize = machine.RomSize()
+		cursor = min(cursor, len(memory)-1)
+
+		switch followSelect.Selected {
+		case "PC":
+			cursor, cursorLength = int(registers.PC), 2
+			list.ScrollTo(cursor / MEMORY_VIEWER_BYTES_PER_ROW)
+		case "I":
+			cursor, cursorLength = int(registers.I), 1
+			list.ScrollTo(cursor / MEMORY_VIEWER_BYTES_PER_ROW)
+		}
+		if getPaused() {
+			valueEntry.Enable()
+			writeButton.Enable()
+		} else {
+			valueEntry.Disable()
+			writeButton.Disable()
+		}
+		cursorLabel.SetText(fmt.Sprintf("Cursor: %04X", cursor))
+		list.Refresh()
+	}
+	refresh()
+
+	go func() {
+		ticker := time.NewTicker(time.Second / MEMORY_VIEWER_REFRESH_RATE)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+				fyne.Do(refresh)
+			}
+		}
+	}()
+
+	toolbar := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Follow"), container.NewHBox(jumpButton), container.NewGridWithColumns(2, followSelect, addressEntry)),
+		container.NewBorder(nil, nil, widget.NewLabel("Search"), searchButton, searchEntry),
+		container.NewBorder(nil, nil, cursorLabel, writeButton, valueEntry),
+	)
+	return container.NewBorder(toolbar, nil, nil, nil, list)
+}
+
+// memoryViewerRow lays out one row of the memory viewer as an address, hex bytes, and their ASCII.
+// Each byte is given the background colour chosen by highlight, if any
+func memoryViewerRow(memory []byte, start int, highlight func(address int) color.Color) widget.TextGridRow {
+	var cells []widget.TextGridCell
+	addText := func(text string, style widget.TextGridStyle) {
+		for _, r := range text {
+			cells = append(cells, widget.TextGridCell{Rune: r, Style: style})
+		}
+	}
+
+	addText(fmt.Sprintf("%04X  ", start), nil)
+	var ascii []widget.TextGridCell
+	for address := start; address < start+MEMORY_VIEWER_BYTES_PER_ROW; address++ {
+		if address >= len(memory) {
+			addText("   ", nil)
+			continue
+		}
+		var style widget.TextGridStyle
+		if background := highlight(address); background != nil {
+			style = &widget.CustomTextGridStyle{BGColor: background}
+		}
+		addText(fmt.Sprintf("%02X", memory[address]), style)
+		addText(" ", nil)
+
+		char := rune(memory[address])
+		if char < 0x20 || char > 0x7E {
+			char = '.'
+		}
+		ascii = append(ascii, widget.TextGridCell{Rune: char, Style: style})
+	}
+	addText(" ", nil)
+	return widget.TextGridRow{Cells: append(cells, ascii...)}
+}
+
+// parseHexBytes reads a sequence of hexadecimal bytes, optionally separated by spaces
+func parseHexBytes(text string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("%q is not a sequence of hexadecimal bytes", text)
+	}
+	return data, nil
+}
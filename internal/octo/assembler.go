@@ -0,0 +1,1022 @@
+// Package octo assembles programs written in the Octo assembly language into CHIP-8 ROM images.
+//
+// Supported are labels, :alias, :const, :macro, :byte, :org, :next, :unpack, :call,
+// if/then, if/begin/else/end, loop/while/again, and the CHIP-8, SUPER-CHIP, and XO-CHIP instructions.
+// Octo's {} expressions, :calc, :stringmode, and :assert are not supported
+package octo
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	ROM_START   = 0x200
+	MAX_ADDRESS = 0xFFFF
+
+	// MAX_MACRO_DEPTH stops a macro which invokes itself from expanding forever
+	MAX_MACRO_DEPTH = 100
+)
+
+// keywords can't be used as names for labels, constants, aliases, or macros
+var keywords = []string{
+	"clear", "return", ";", "jump", "jump0", "native", "sprite", "save", "load", "saveflags", "loadflags",
+	"bcd", "delay", "buzzer", "pitch", "plane", "audio", "scroll-down", "scroll-up", "scroll-left",
+	"scroll-right", "exit", "lores", "hires", "i", "if", "then", "begin", "else", "end", "loop", "while",
+	"again", "key", "-key", "random", "hex", "bighex", "long",
+}
+
+type fixupKind int
+
+const (
+	// FIXUP_ADDRESS fills in the 12-bit NNN of an opcode
+	FIXUP_ADDRESS fixupKind = iota
+	// FIXUP_LONG fills in a whole 16-bit word
+	FIXUP_LONG
+	// FIXUP_UNPACK_HIGH fills in the low nibble of a byte with bits 8-11 of the address
+	FIXUP_UNPACK_HIGH
+	// FIXUP_UNPACK_LOW fills in a byte with the low 8 bits of the address
+	FIXUP_UNPACK_LOW
+)
+
+// fixup is a reference to a label that hadn't been defined yet, to be filled in once assembly finishes
+type fixup struct {
+	address int
+	kind    fixupKind
+	label   token
+}
+
+type macro struct {
+	params []string
+	body   []token
+}
+
+// conditional is an open if ... begin block, waiting for its else or end
+type conditional struct {
+	// jump is the address of the jump which skips the block when the condition fails
+	jump    int
+	hasElse bool
+	start   token
+}
+
+// loop is an open loop block, waiting for its again
+type loop struct {
+	address int
+	// breaks are the addresses of the jumps out of the loop made by while
+	breaks []int
+	start  token
+}
+
+// condition is a comparison made by if or while, such as "v0 == 5"
+type condition struct {
+	x  uint8
+	op token
+	// y is the register compared against, if isRegister, otherwise nn is the value compared against
+	y          uint8
+	nn         uint8
+	isRegister bool
+}
+
+type assembler struct {
+	tokens []token
+	pos    int
+
+	rom     []byte
+	address int
+
+	labels    map[string]int
+	constants map[string]int
+	aliases   map[string]uint8
+	macros    map[string]macro
+
+	fixups       []fixup
+	conditionals []conditional
+	loops        []loop
+	// overflow is set when the ROM runs past the end of memory, for the statement responsible to report
+	overflow bool
+}
+
+// Assemble builds a ROM image from Octo source, to be loaded at 0x200.
+// Execution starts at the label main. A jump to main is placed at 0x200, unless main comes before any code or data
+func Assemble(source string) ([]byte, error) {
+	a := &assembler{
+		tokens:    tokenize(source),
+		address:   ROM_START,
+		labels:    map[string]int{},
+		constants: map[string]int{},
+		aliases:   map[string]uint8{},
+		macros:    map[string]macro{},
+	}
+
+	for i := 0; i+1 < len(a.tokens); i++ {
+		if a.tokens[i].text == ":" && a.tokens[i+1].text == "main" {
+			// Dropped again by defineLabel if main turns out to come first
+			a.addFixup(ROM_START, FIXUP_ADDRESS, a.tokens[i+1])
+			a.emitOp(0x1000)
+			break
+		}
+	}
+
+	for a.pos < len(a.tokens) {
+		start := a.tokens[a.pos]
+		err := a.statement()
+		if err == nil && a.overflow {
+			err = errorAt(start, "the program runs past the end of memory at 0x%X", MAX_ADDRESS)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(a.conditionals) > 0 {
+		return nil, errorAt(a.conditionals[len(a.conditionals)-1].start, "if ... begin is missing its end")
+	}
+	if len(a.loops) > 0 {
+		return nil, errorAt(a.loops[len(a.loops)-1].start, "loop is missing its again")
+	}
+
+	err := a.resolveFixups()
+	if err != nil {
+		return nil, err
+	}
+	return a.rom, nil
+}
+
+// next consumes the next token, which the statement being assembled needs
+func (a *assembler) next() (token, error) {
+	if a.pos >= len(a.tokens) {
+		return token{}, errorAt(a.tokens[len(a.tokens)-1], "unexpected end of file")
+	}
+	tok := a.tokens[a.pos]
+	a.pos++
+	return tok, nil
+}
+
+// peek returns the next token without consuming it, or false at the end of the file
+func (a *assembler) peek() (token, bool) {
+	if a.pos >= len(a.tokens) {
+		return token{}, false
+	}
+	return a.tokens[a.pos], true
+}
+
+func (a *assembler) expect(text string) error {
+	tok, err := a.next()
+	if err != nil {
+		return err
+	}
+	if tok.text != text {
+		return errorAt(tok, "expected %q, got %q", text, tok.text)
+	}
+	return nil
+}
+
+// emit adds bytes to the ROM at the current address. Anything past the end of memory is dropped, and flagged as an overflow
+func (a *assembler) emit(data ...byte) {
+	for _, b := range data {
+		if a.address > MAX_ADDRESS {
+			a.overflow = true
+			return
+		}
+		offset := a.address - ROM_START
+		if offset >= len(a.rom) {
+			a.rom = append(a.rom, make([]byte, offset-len(a.rom)+1)...)
+		}
+		a.rom[offset] = b
+		a.address++
+	}
+}
+
+func (a *assembler) emitOp(opcode uint16) {
+	a.emit(byte(opcode>>8), byte(opcode))
+}
+
+func (a *assembler) addFixup(address int, kind fixupKind, label token) {
+	a.fixups = append(a.fixups, fixup{address: address, kind: kind, label: label})
+}
+
+// patchAddress fills in the NNN of the opcode at an address
+func (a *assembler) patchAddress(at int, target int, tok token) error {
+	if target > 0xFFF {
+		return errorAt(tok, "address 0x%X is out of reach of a 12-bit address", target)
+	}
+	offset := at - ROM_START
+	a.rom[offset] = a.rom[offset]&0xF0 | byte(target>>8)
+	a.rom[offset+1] = byte(target)
+	return nil
+}
+
+func (a *assembler) statement() error {
+	tok, err := a.next()
+	if err != nil {
+		return err
+	}
+
+	switch tok.text {
+	case ":":
+		name, err := a.next()
+		if err != nil {
+			return err
+		}
+		return a.defineLabel(name, a.address)
+	case ":next":
+		// Labels the second byte of the next instruction, for self-modifying code
+		name, err := a.next()
+		if err != nil {
+			return err
+		}
+		return a.defineLabel(name, a.address+1)
+	case ":alias":
+		return a.aliasDirective()
+	case ":const":
+		return a.constDirective()
+	case ":macro":
+		return a.macroDirective()
+	case ":byte":
+		value, err := a.byteOperand()
+		if err != nil {
+			return err
+		}
+		a.emit(value)
+	case ":org":
+		valueToken, err := a.next()
+		if err != nil {
+			return err
+		}
+		value, ok := a.number(valueToken)
+		if !ok || value < ROM_START || value > MAX_ADDRESS {
+			return errorAt(valueToken, "expected an address from 0x200 to 0xFFFF, got %q", valueToken.text)
+		}
+		a.address = value
+	case ":unpack":
+		return a.unpackDirective()
+	case ":call":
+		return a.addressOp(0x2000)
+	case ":breakpoint":
+		// Breakpoints are set from the debugger instead, so just skip the name
+		_, err := a.next()
+		return err
+	case ":monitor":
+		for range 2 {
+			_, err := a.next()
+			if err != nil {
+				return err
+			}
+		}
+	case "clear":
+		a.emitOp(0x00E0)
+	case "return", ";":
+		a.emitOp(0x00EE)
+	case "jump":
+		return a.addressOp(0x1000)
+	case "jump0":
+		return a.addressOp(0xB000)
+	case "native":
+		return a.addressOp(0x0000)
+	case "sprite":
+		x, err := a.registerOperand()
+		if err != nil {
+			return err
+		}
+		y, err := a.registerOperand()
+		if err != nil {
+			return err
+		}
+		n, err := a.nibbleOperand(15)
+		if err != nil {
+			return err
+		}
+		a.emitOp(0xD000 | uint16(x)<<8 | uint16(y)<<4 | uint16(n))
+	case "save", "load":
+		return a.saveLoadStatement(tok.text == "save")
+	case "saveflags":
+		return a.registerOp(0xF075)
+	case "loadflags":
+		return a.registerOp(0xF085)
+	case "bcd":
+		return a.registerOp(0xF033)
+	case "delay", "buzzer", "pitch":
+		err := a.expect(":=")
+		if err != nil {
+			return err
+		}
+		opcodes := map[string]uint16{"delay": 0xF015, "buzzer": 0xF018, "pitch": 0xF03A}
+		return a.registerOp(opcodes[tok.text])
+	case "plane":
+		n, err := a.nibbleOperand(3)
+		if err != nil {
+			return err
+		}
+		a.emitOp(0xF001 | uint16(n)<<8)
+	case "audio":
+		a.emitOp(0xF002)
+	case "scroll-down":
+		n, err := a.nibbleOperand(15)
+		if err != nil {
+			return err
+		}
+		a.emitOp(0x00C0 | uint16(n))
+	case "scroll-up":
+		n, err := a.nibbleOperand(15)
+		if err != nil {
+			return err
+		}
+		a.emitOp(0x00D0 | uint16(n))
+	case "scroll-right":
+		a.emitOp(0x00FB)
+	case "scroll-left":
+		a.emitOp(0x00FC)
+	case "exit":
+		a.emitOp(0x00FD)
+	case "lores":
+		a.emitOp(0x00FE)
+	case "hires":
+		a.emitOp(0x00FF)
+	case "i":
+		return a.indexStatement()
+	case "if":
+		return a.ifStatement(tok)
+	case "else":
+		return a.elseStatement(tok)
+	case "end":
+		return a.endStatement(tok)
+	case "loop":
+		a.loops = append(a.loops, loop{address: a.address, start: tok})
+	case "while":
+		return a.whileStatement(tok)
+	case "again":
+		return a.againStatement(tok)
+	default:
+		if x, ok := a.register(tok); ok {
+			return a.registerStatement(x)
+		}
+		if m, ok := a.macros[tok.text]; ok {
+			return a.expandMacro(tok, m)
+		}
+		if value, ok := a.number(tok); ok && !a.isLabel(tok.text) {
+			// Numbers on their own are emitted as data
+			if value < -128 || value > 255 {
+				return errorAt(tok, "%q does not fit in a byte", tok.text)
+			}
+			a.emit(byte(value))
+			return nil
+		}
+		if tok.text[0] == ':' {
+			return errorAt(tok, "unknown or unsupported directive %q", tok.text)
+		}
+		if !isIdentifier(tok.text) {
+			return errorAt(tok, "unexpected %q", tok.text)
+		}
+		// Any other name is a call to the subroutine with that label
+		a.pos--
+		return a.addressOp(0x2000)
+	}
+	return nil
+}
+
+// defineLabel names an address, which may already have been referred to
+func (a *assembler) defineLabel(name token, address int) error {
+	err := a.checkName(name)
+	if err != nil {
+		return err
+	}
+	if name.text == "main" && address == ROM_START+2 && len(a.rom) == 2 && len(a.fixups) == 1 {
+		// Nothing but the jump to main comes before it, so there's no need to jump at all
+		a.rom = a.rom[:0]
+		a.fixups = a.fixups[:0]
+		address = ROM_START
+		a.address = ROM_START
+	}
+	a.labels[name.text] = address
+	return nil
+}
+
+// checkName makes sure a new label, constant, alias, or macro name is valid and not already taken
+func (a *assembler) checkName(name token) error {
+	if !isIdentifier(name.text) || slices.Contains(keywords, name.text) {
+		return errorAt(name, "%q can't be used as a name", name.text)
+	}
+	if _, ok := a.register(name); ok {
+		return errorAt(name, "%q is already a register", name.text)
+	}
+	_, isConstant := a.constants[name.text]
+	_, isMacro := a.macros[name.text]
+	if a.isLabel(name.text) || isConstant || isMacro {
+		return errorAt(name, "%q is already defined", name.text)
+	}
+	return nil
+}
+
+func (a *assembler) isLabel(name string) bool {
+	_, ok := a.labels[name]
+	return ok
+}
+
+func (a *assembler) aliasDirective() error {
+	name, err := a.next()
+	if err != nil {
+		return err
+	}
+	if _, isAlias := a.aliases[name.text]; !isAlias {
+		// Aliases may be pointed at a different register later on
+		err = a.checkName(name)
+		if err != nil {
+			return err
+		}
+	}
+	x, err := a.registerOperand()
+	if err != nil {
+		return err
+	}
+	a.aliases[name.text] = x
+	return nil
+}
+
+func (a *assembler) constDirective() error {
+	name, err := a.next()
+	if err != nil {
+		return err
+	}
+	err = a.checkName(name)
+	if err != nil {
+		return err
+	}
+	valueToken, err := a.next()
+	if err != nil {
+		return err
+	}
+	value, ok := a.number(valueToken)
+	if !ok {
+		return errorAt(valueToken, "expected a number, got %q", valueToken.text)
+	}
+	a.constants[name.text] = value
+	return nil
+}
+
+// macroDirective records a macro's parameters and body, for expansion wherever its name is used
+func (a *assembler) macroDirective() error {
+	name, err := a.next()
+	if err != nil {
+		return err
+	}
+	err = a.checkName(name)
+	if err != nil {
+		return err
+	}
+
+	var m macro
+	for {
+		tok, err := a.next()
+		if err != nil {
+			return err
+		}
+		if tok.text == "{" {
+			break
+		}
+		m.params = append(m.params, tok.text)
+	}
+	depth := 1
+	for {
+		tok, err := a.next()
+		if err != nil {
+			return errorAt(name, "macro %q is missing its closing }", name.text)
+		}
+		if tok.text == "{" {
+			depth++
+		} else if tok.text == "}" {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+		m.body = append(m.body, tok)
+	}
+	a.macros[name.text] = m
+	return nil
+}
+
+// expandMacro replaces a macro's invocation with its body, substituting in the arguments that follow it
+func (a *assembler) expandMacro(invocation token, m macro) error {
+	if invocation.depth >= MAX_MACRO_DEPTH {
+		return errorAt(invocation, "macros nested too deeply, %q may be invoking itself", invocation.text)
+	}
+
+	args := map[string]string{}
+	for _, param := range m.params {
+		arg, err := a.next()
+		if err != nil {
+			return err
+		}
+		args[param] = arg.text
+	}
+	body := make([]token, len(m.body))
+	for i, tok := range m.body {
+		body[i] = tok
+		body[i].depth = invocation.depth + 1
+		if arg, ok := args[tok.text]; ok {
+			body[i].text = arg
+		}
+	}
+	a.tokens = slices.Insert(a.tokens, a.pos, body...)
+	return nil
+}
+
+// unpackDirective loads a 12-bit address into v0 and v1, with a nibble in the top of v0
+func (a *assembler) unpackDirective() error {
+	n, err := a.nibbleOperand(15)
+	if err != nil {
+		return err
+	}
+	label, err := a.next()
+	if err != nil {
+		return err
+	}
+	value, ok := a.number(label)
+	if !ok {
+		if !isIdentifier(label.text) {
+			return errorAt(label, "expected an address, got %q", label.text)
+		}
+		a.addFixup(a.address+1, FIXUP_UNPACK_HIGH, label)
+		a.addFixup(a.address+3, FIXUP_UNPACK_LOW, label)
+	}
+	a.emitOp(0x6000 | uint16(n)<<4 | uint16(value>>8)&0x0F)
+	a.emitOp(0x6100 | uint16(value)&0xFF)
+	return nil
+}
+
+// addressOp emits an opcode taking a 12-bit address, such as a jump or call
+func (a *assembler) addressOp(opcode uint16) error {
+	tok, err := a.next()
+	if err != nil {
+		return err
+	}
+	value, ok := a.number(tok)
+	if !ok {
+		if !isIdentifier(tok.text) {
+			return errorAt(tok, "expected an address, got %q", tok.text)
+		}
+		a.addFixup(a.address, FIXUP_ADDRESS, tok)
+	}
+	if value < 0 || value > 0xFFF {
+		return errorAt(tok, "address %q is out of reach of a 12-bit address", tok.text)
+	}
+	a.emitOp(opcode | uint16(value))
+	return nil
+}
+
+// registerOp emits an opcode taking a single register as X, such as FX33
+func (a *assembler) registerOp(opcode uint16) error {
+	x, err := a.registerOperand()
+	if err != nil {
+		return err
+	}
+	a.emitOp(opcode | uint16(x)<<8)
+	return nil
+}
+
+func (a *assembler) saveLoadStatement(save bool) error {
+	x, err := a.registerOperand()
+	if err != nil {
+		return err
+	}
+	if tok, ok := a.peek(); ok && tok.text == "-" {
+		// save vx - vy and load vx - vy are the XO-CHIP register range instructions
+		a.pos++
+		y, err := a.registerOperand()
+		if err != nil {
+			return err
+		}
+		opcode := uint16(0x5003)
+		if save {
+			opcode = 0x5002
+		}
+		a.emitOp(opcode | uint16(x)<<8 | uint16(y)<<4)
+		return nil
+	}
+	opcode := uint16(0xF065)
+	if save {
+		opcode = 0xF055
+	}
+	a.emitOp(opcode | uint16(x)<<8)
+	return nil
+}
+
+func (a *assembler) indexStatement() error {
+	op, err := a.next()
+	if err != nil {
+		return err
+	}
+	switch op.text {
+	case ":=":
+		tok, ok := a.peek()
+		if !ok {
+			return errorAt(op, "unexpected end of file")
+		}
+		switch tok.text {
+		case "hex":
+			a.pos++
+			return a.registerOp(0xF029)
+		case "bighex":
+			a.pos++
+			return a.registerOp(0xF030)
+		case "long":
+			a.pos++
+			return a.longAddress()
+		}
+		return a.addressOp(0xA000)
+	case "+=":
+		return a.registerOp(0xF01E)
+	}
+	return errorAt(op, "expected := or += after i, got %q", op.text)
+}
+
+// longAddress emits the XO-CHIP F000 NNNN instruction, which loads a 16-bit address into I
+func (a *assembler) longAddress() error {
+	tok, err := a.next()
+	if err != nil {
+		return err
+	}
+	a.emitOp(0xF000)
+	value, ok := a.number(tok)
+	if !ok {
+		if !isIdentifier(tok.text) {
+			return errorAt(tok, "expected an address, got %q", tok.text)
+		}
+		a.addFixup(a.address, FIXUP_LONG, tok)
+	}
+	if value < 0 || value > MAX_ADDRESS {
+		return errorAt(tok, "address %q doesn't fit in 16 bits", tok.text)
+	}
+	a.emitOp(uint16(value))
+	return nil
+}
+
+// registerStatement assembles an assignment or arithmetic operation on VX
+func (a *assembler) registerStatement(x uint8) error {
+	op, err := a.next()
+	if err != nil {
+		return err
+	}
+	registerOps := map[string]uint16{"|=": 0x1, "&=": 0x2, "^=": 0x3, ">>=": 0x6, "=-": 0x7, "<<=": 0xE}
+
+	switch op.text {
+	case ":=":
+		source, err := a.next()
+		if err != nil {
+			return err
+		}
+		switch source.text {
+		case "random":
+			nn, err := a.byteOperand()
+			if err != nil {
+				return err
+			}
+			a.emitOp(0xC000 | uint16(x)<<8 | uint16(nn))
+			return nil
+		case "key":
+			a.emitOp(0xF00A | uint16(x)<<8)
+			return nil
+		case "delay":
+			a.emitOp(0xF007 | uint16(x)<<8)
+			return nil
+		}
+		if y, ok := a.register(source); ok {
+			a.emitOp(0x8000 | uint16(x)<<8 | uint16(y)<<4)
+			return nil
+		}
+		a.pos--
+		nn, err := a.byteOperand()
+		if err != nil {
+			return err
+		}
+		a.emitOp(0x6000 | uint16(x)<<8 | uint16(nn))
+	case "+=", "-=":
+		source, err := a.next()
+		if err != nil {
+			return err
+		}
+		if y, ok := a.register(source); ok {
+			n := uint16(0x4)
+			if op.text == "-=" {
+				n = 0x5
+			}
+			a.emitOp(0x8000 | uint16(x)<<8 | uint16(y)<<4 | n)
+			return nil
+		}
+		a.pos--
+		nn, err := a.byteOperand()
+		if err != nil {
+			return err
+		}
+		if op.text == "-=" {
+			// There's no subtract immediate instruction, so add the two's complement instead
+			nn = -nn
+		}
+		a.emitOp(0x7000 | uint16(x)<<8 | uint16(nn))
+	default:
+		n, ok := registerOps[op.text]
+		if !ok {
+			return errorAt(op, "expected an operator after V%X, got %q", x, op.text)
+		}
+		y, err := a.registerOperand()
+		if err != nil {
+			return err
+		}
+		a.emitOp(0x8000 | uint16(x)<<8 | uint16(y)<<4 | n)
+	}
+	return nil
+}
+
+func (a *assembler) parseCondition() (condition, error) {
+	var c condition
+	x, err := a.registerOperand()
+	if err != nil {
+		return c, err
+	}
+	c.x = x
+	c.op, err = a.next()
+	if err != nil {
+		return c, err
+	}
+
+	switch c.op.text {
+	case "key", "-key":
+		return c, nil
+	case "==", "!=", "<", ">", "<=", ">=":
+		operand, err := a.next()
+		if err != nil {
+			return c, err
+		}
+		if y, ok := a.register(operand); ok {
+			c.y, c.isRegister = y, true
+			return c, nil
+		}
+		a.pos--
+		c.nn, err = a.byteOperand()
+		return c, err
+	}
+	return c, errorAt(c.op, "expected a comparison such as == or key, got %q", c.op.text)
+}
+
+// emitSkip emits the instructions that skip the next instruction, either when the condition is true or when it's false
+func (a *assembler) emitSkip(c condition, skipWhenTrue bool) {
+	switch c.op.text {
+	case "==", "!=":
+		// Skip when equal if the condition is == and we skip when true, or != and we skip when false
+		skipWhenEqual := (c.op.text == "==") == skipWhenTrue
+		switch {
+		case c.isRegister && skipWhenEqual:
+			a.emitOp(0x5000 | uint16(c.x)<<8 | uint16(c.y)<<4)
+		case c.isRegister:
+			a.emitOp(0x9000 | uint16(c.x)<<8 | uint16(c.y)<<4)
+		case skipWhenEqual:
+			a.emitOp(0x3000 | uint16(c.x)<<8 | uint16(c.nn))
+		default:
+			a.emitOp(0x4000 | uint16(c.x)<<8 | uint16(c.nn))
+		}
+	case "key", "-key":
+		if (c.op.text == "key") == skipWhenTrue {
+			a.emitOp(0xE09E | uint16(c.x)<<8)
+		} else {
+			a.emitOp(0xE0A1 | uint16(c.x)<<8)
+		}
+	default:
+		// <, >, <=, and >= are built out of a subtraction in VF, whose flag is set when the left side >= the right side.
+		// > and <= are handled by swapping the sides over
+		swap := c.op.text == ">" || c.op.text == "<="
+		switch {
+		case c.isRegister && swap:
+			a.emitOp(0x8F00 | uint16(c.y)<<4)
+			a.emitOp(0x8F05 | uint16(c.x)<<4)
+		case c.isRegister:
+			a.emitOp(0x8F00 | uint16(c.x)<<4)
+			a.emitOp(0x8F05 | uint16(c.y)<<4)
+		case swap:
+			a.emitOp(0x6F00 | uint16(c.nn))
+			a.emitOp(0x8F05 | uint16(c.x)<<4)
+		default:
+			a.emitOp(0x6F00 | uint16(c.nn))
+			a.emitOp(0x8F07 | uint16(c.x)<<4)
+		}
+		trueWhenFlagSet := c.op.text == ">=" || c.op.text == "<="
+		if trueWhenFlagSet == skipWhenTrue {
+			a.emitOp(0x3F01)
+		} else {
+			a.emitOp(0x3F00)
+		}
+	}
+}
+
+func (a *assembler) ifStatement(start token) error {
+	c, err := a.parseCondition()
+	if err != nil {
+		return err
+	}
+	body, err := a.next()
+	if err != nil {
+		return err
+	}
+	switch body.text {
+	case "then":
+		// The next statement is skipped unless the condition is true
+		a.emitSkip(c, false)
+	case "begin":
+		// Jump past the block unless the condition is true. The jump is filled in by else or end
+		a.emitSkip(c, true)
+		a.conditionals = append(a.conditionals, conditional{jump: a.address, start: start})
+		a.emitOp(0x1000)
+	default:
+		return errorAt(body, "expected then or begin, got %q", body.text)
+	}
+	return nil
+}
+
+func (a *assembler) elseStatement(tok token) error {
+	if len(a.conditionals) == 0 || a.conditionals[len(a.conditionals)-1].hasElse {
+		return errorAt(tok, "else without a matching if ... begin")
+	}
+	c := &a.conditionals[len(a.conditionals)-1]
+	// The end of the true block jumps past the else block
+	jump := a.address
+	a.emitOp(0x1000)
+	err := a.patchAddress(c.jump, a.address, tok)
+	if err != nil {
+		return err
+	}
+	c.jump = jump
+	c.hasElse = true
+	return nil
+}
+
+func (a *assembler) endStatement(tok token) error {
+	if len(a.conditionals) == 0 {
+		return errorAt(tok, "end without a matching if ... begin")
+	}
+	c := a.conditionals[len(a.conditionals)-1]
+	a.conditionals = a.conditionals[:len(a.conditionals)-1]
+	return a.patchAddress(c.jump, a.address, tok)
+}
+
+func (a *assembler) whileStatement(tok token) error {
+	if len(a.loops) == 0 {
+		return errorAt(tok, "while outside of a loop")
+	}
+	c, err := a.parseCondition()
+	if err != nil {
+		return err
+	}
+	// Carry on looping while the condition is true, otherwise jump out. The jump is filled in by again
+	a.emitSkip(c, true)
+	l := &a.loops[len(a.loops)-1]
+	l.breaks = append(l.breaks, a.address)
+	a.emitOp(0x1000)
+	return nil
+}
+
+func (a *assembler) againStatement(tok token) error {
+	if len(a.loops) == 0 {
+		return errorAt(tok, "again without a matching loop")
+	}
+	l := a.loops[len(a.loops)-1]
+	a.loops = a.loops[:len(a.loops)-1]
+
+	if l.address > 0xFFF {
+		return errorAt(l.start, "loop at 0x%X is out of reach of a 12-bit address", l.address)
+	}
+	a.emitOp(0x1000 | uint16(l.address))
+	for _, jump := range l.breaks {
+		err := a.patchAddress(jump, a.address, tok)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveFixups fills in references to labels that were defined after they were used
+func (a *assembler) resolveFixups() error {
+	for _, f := range a.fixups {
+		address, ok := a.labels[f.label.text]
+		if !ok {
+			return errorAt(f.label, "undefined label %q", f.label.text)
+		}
+		offset := f.address - ROM_START
+		switch f.kind {
+		case FIXUP_ADDRESS:
+			err := a.patchAddress(f.address, address, f.label)
+			if err != nil {
+				return err
+			}
+		case FIXUP_LONG:
+			a.rom[offset] = byte(address >> 8)
+			a.rom[offset+1] = byte(address)
+		case FIXUP_UNPACK_HIGH:
+			if address > 0xFFF {
+				return errorAt(f.label, "address 0x%X is out of reach of a 12-bit address", address)
+			}
+			a.rom[offset] |= byte(address>>8) & 0x0F
+		case FIXUP_UNPACK_LOW:
+			a.rom[offset] = byte(address)
+		}
+	}
+	return nil
+}
+
+// register reads a register name such as v3 or vA, or an alias for one
+func (a *assembler) register(tok token) (uint8, bool) {
+	if x, ok := a.aliases[tok.text]; ok {
+		return x, true
+	}
+	if len(tok.text) != 2 || (tok.text[0] != 'v' && tok.text[0] != 'V') {
+		return 0, false
+	}
+	x, err := strconv.ParseUint(tok.text[1:], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(x), true
+}
+
+func (a *assembler) registerOperand() (uint8, error) {
+	tok, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+	x, ok := a.register(tok)
+	if !ok {
+		return 0, errorAt(tok, "expected a register, got %q", tok.text)
+	}
+	return x, nil
+}
+
+// number reads a decimal, 0x hexadecimal, or 0b binary number, or the value of a constant or already defined label
+func (a *assembler) number(tok token) (int, bool) {
+	if value, ok := a.constants[tok.text]; ok {
+		return value, true
+	}
+	if value, ok := a.labels[tok.text]; ok {
+		return value, true
+	}
+	return parseNumber(tok.text)
+}
+
+// parseNumber reads a decimal, 0x hexadecimal, or 0b binary number, which may be negative.
+// Unlike Go, a leading 0 doesn't make a number octal, and underscores aren't allowed between digits
+func parseNumber(text string) (int, bool) {
+	digits, negative := strings.CutPrefix(text, "-")
+	base := 10
+	if rest, ok := strings.CutPrefix(digits, "0x"); ok {
+		digits, base = rest, 16
+	} else if rest, ok := strings.CutPrefix(digits, "0b"); ok {
+		digits, base = rest, 2
+	}
+	// ParseUint rejects signs and underscores when given a base
+	value, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		return -int(value), true
+	}
+	return int(value), true
+}
+
+// byteOperand reads a byte, which may be written as a negative number
+func (a *assembler) byteOperand() (uint8, error) {
+	tok, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+	value, ok := a.number(tok)
+	if !ok {
+		return 0, errorAt(tok, "expected a byte, got %q", tok.text)
+	}
+	if value < -128 || value > 255 {
+		return 0, errorAt(tok, "%q does not fit in a byte", tok.text)
+	}
+	return uint8(value), nil
+}
+
+// nibbleOperand reads a small number, from 0 up to max
+func (a *assembler) nibbleOperand(max int) (uint8, error) {
+	tok, err := a.next()
+	if err != nil {
+		return 0, err
+	}
+	value, ok := a.number(tok)
+	if !ok || value < 0 || value > max {
+		return 0, errorAt(tok, "expected a number from 0 to %d, got %q", max, tok.text)
+	}
+	return uint8(value), nil
+}
+
+// isIdentifier reports whether text can be used as a name, such as a label
+func isIdentifier(text string) bool {
+	for i, r := range text {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && (r == '-' || unicode.IsDigit(r)))) {
+			return false
+		}
+	}
+	return text != ""
+}
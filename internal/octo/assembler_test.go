@@ -0,0 +1,148 @@
+package octo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// ops encodes opcodes as the big-endian bytes of a ROM
+func ops(opcodes ...uint16) []byte {
+	var rom []byte
+	for _, opcode := range opcodes {
+		rom = append(rom, byte(opcode>>8), byte(opcode))
+	}
+	return rom
+}
+
+var assembleTests = []struct {
+	name     string
+	source   string
+	expected []byte
+}{
+	// Statements
+	{"clear", "clear", ops(0x00E0)},
+	{"return", "return ;", ops(0x00EE, 0x00EE)},
+	{"jump", "jump 0x300", ops(0x1300)},
+	{"jump0", "jump0 0x300", ops(0xB300)},
+	{"native", "native 0x123", ops(0x0123)},
+	{":call", ":call 0x300", ops(0x2300)},
+	{"sprite", "sprite v1 v2 5", ops(0xD125)},
+	{"save and load", "save v3 load v3", ops(0xF355, 0xF365)},
+	{"save and load a range", "save v1 - v3 load v1 - v3", ops(0x5132, 0x5133)},
+	{"flags", "saveflags v2 loadflags v2", ops(0xF275, 0xF285)},
+	{"bcd", "bcd v4", ops(0xF433)},
+	{"timers", "delay := v1 buzzer := v1 pitch := v1", ops(0xF115, 0xF118, 0xF13A)},
+	{"plane and audio", "plane 3 audio", ops(0xF301, 0xF002)},
+	{"scrolling", "scroll-down 4 scroll-up 4 scroll-right scroll-left", ops(0x00C4, 0x00D4, 0x00FB, 0x00FC)},
+	{"display modes", "exit lores hires", ops(0x00FD, 0x00FE, 0x00FF)},
+	{"i", "i := 0x345 i := hex v2 i := bighex v2 i += v3", ops(0xA345, 0xF229, 0xF230, 0xF31E)},
+	{"i := long", "i := long 0x1234", ops(0xF000, 0x1234)},
+	{"register loads", "v1 := 0x12 v1 := v2 v1 := random 0x0F v1 := key v1 := delay", ops(0x6112, 0x8120, 0xC10F, 0xF10A, 0xF107)},
+	{"register adds", "v1 += 5 v1 -= 1 v1 += v2 v1 -= v2", ops(0x7105, 0x71FF, 0x8124, 0x8125)},
+	{"register operators", "v1 |= v2 v1 &= v2 v1 ^= v2 v1 >>= v2 v1 =- v2 v1 <<= v2", ops(0x8121, 0x8122, 0x8123, 0x8126, 0x8127, 0x812E)},
+	{"data", ":byte 0xAB 0x12 34 -1 0b101", []byte{0xAB, 0x12, 34, 0xFF, 5}},
+	{"leading zeros are decimal", "v1 := 010", ops(0x610A)},
+	{":org", ":org 0x204 clear", append(make([]byte, 4), 0x00, 0xE0)},
+	{":const", ":const five 5 v1 := five", ops(0x6105)},
+	{":alias", ":alias x v3 x := 1", ops(0x6301)},
+	{"comments", "clear # return\nreturn", ops(0x00E0, 0x00EE)},
+
+	// Conditions, each skipping the statement after then when false
+	{"if == then", "if v1 == 5 then clear", ops(0x4105, 0x00E0)},
+	{"if != then", "if v1 != 5 then clear", ops(0x3105, 0x00E0)},
+	{"if register == then", "if v1 == v2 then clear", ops(0x9120, 0x00E0)},
+	{"if key then", "if v1 key then clear", ops(0xE1A1, 0x00E0)},
+	{"if -key then", "if v1 -key then clear", ops(0xE19E, 0x00E0)},
+	{"if < then", "if v1 < 5 then clear", ops(0x6F05, 0x8F17, 0x3F01, 0x00E0)},
+	{"if > then", "if v1 > 5 then clear", ops(0x6F05, 0x8F15, 0x3F01, 0x00E0)},
+	{"if <= then", "if v1 <= 5 then clear", ops(0x6F05, 0x8F15, 0x3F00, 0x00E0)},
+	{"if >= then", "if v1 >= 5 then clear", ops(0x6F05, 0x8F17, 0x3F00, 0x00E0)},
+	{"if register < then", "if v1 < v2 then clear", ops(0x8F10, 0x8F25, 0x3F01, 0x00E0)},
+	{"if register > then", "if v1 > v2 then clear", ops(0x8F20, 0x8F15, 0x3F01, 0x00E0)},
+	{"if register <= then", "if v1 <= v2 then clear", ops(0x8F20, 0x8F15, 0x3F00, 0x00E0)},
+	{"if register >= then", "if v1 >= v2 then clear", ops(0x8F10, 0x8F25, 0x3F00, 0x00E0)},
+	{"if begin end", "if v1 == 5 begin clear end", ops(0x3105, 0x1206, 0x00E0)},
+	{"if begin else end", "if v1 == 5 begin clear else return end", ops(0x3105, 0x1208, 0x00E0, 0x120A, 0x00EE)},
+	{"loop again", "loop clear again", ops(0x00E0, 0x1200)},
+	{"loop while again", "loop v1 += 1 while v1 != 10 again", ops(0x7101, 0x410A, 0x1208, 0x1200)},
+	{"loop while < again", "loop while v1 < 3 again", ops(0x6F03, 0x8F17, 0x3F00, 0x120A, 0x1200)},
+
+	// Labels
+	{"backward label", ": top clear jump top", ops(0x00E0, 0x1200)},
+	{"forward label", "jump done clear : done return", ops(0x1204, 0x00E0, 0x00EE)},
+	{"call by name", "draw : draw return", ops(0x2202, 0x00EE)},
+	{"jump to main", "clear : main return", ops(0x1204, 0x00E0, 0x00EE)},
+	{"main first", ": main clear", ops(0x00E0)},
+	{":next", ":next operand v1 := 7 i := operand", ops(0x6107, 0xA201)},
+	{":unpack", ":unpack 1 0x345", ops(0x6013, 0x6145)},
+	{":unpack a label", ":unpack 0xA data : data 0x12", append(ops(0x60A2, 0x6104), 0x12)},
+	{"i := long label", "i := long data : data 0x12", append(ops(0xF000, 0x0204), 0x12)},
+
+	// Macros
+	{"macro", ":macro twice r { r += 1 r += 1 } twice v2", ops(0x7201, 0x7201)},
+	{"nested macros", ":macro inc r { r += 1 } :macro inc2 r { inc r inc r } inc2 v3", ops(0x7301, 0x7301)},
+	{"macro with braces", ":macro block { if v0 == 0 begin clear end } block", ops(0x3000, 0x1206, 0x00E0)},
+	{"many macro expansions", ":macro inc { v1 += 1 } " + strings.Repeat("inc ", 20000), bytes.Repeat(ops(0x7101), 20000)},
+}
+
+func TestAssemble(t *testing.T) {
+	for _, test := range assembleTests {
+		t.Run(test.name, func(t *testing.T) {
+			rom, err := Assemble(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rom, test.expected) {
+				t.Errorf("Assemble(%q) = % X, expected % X", test.source, rom, test.expected)
+			}
+		})
+	}
+}
+
+var errorTests = []struct {
+	name    string
+	source  string
+	line    int
+	column  int
+	message string
+}{
+	{"undefined label", "clear\n  jump nowhere", 2, 8, `undefined label "nowhere"`},
+	{"undefined subroutine", "  draw", 1, 3, `undefined label "draw"`},
+	{"byte too large", "v1 := 300", 1, 7, "does not fit in a byte"},
+	{"octal number", "v1 := 0o7", 1, 7, "expected a byte"},
+	{"underscores in numbers", "v1 := 1_000", 1, 7, "expected a byte"},
+	{"register as a label", ": v1", 1, 3, "already a register"},
+	{"label defined twice", ": a\n: a", 2, 3, "already defined"},
+	{"unknown directive", ":stringmode", 1, 1, "unknown or unsupported directive"},
+	{"unknown operator", "v1 %= v2", 1, 4, "expected an operator"},
+	{"bad condition", "if v1 = 2 then clear", 1, 7, "expected a comparison"},
+	{"if without then", "if v1 == 2 clear", 1, 12, "expected then or begin"},
+	{"missing end", "if v1 == 5 begin\nclear", 1, 1, "missing its end"},
+	{"missing again", "\n  loop\nclear", 2, 3, "missing its again"},
+	{"else without if", "clear else", 1, 7, "else without"},
+	{"end without if", "end", 1, 1, "end without"},
+	{"again without loop", "again", 1, 1, "again without"},
+	{"while outside loop", "while v0 == 1", 1, 1, "while outside"},
+	{"long address too large", "i := long 0x10000", 1, 11, "doesn't fit in 16 bits"},
+	{"address out of reach", ":org 0x1000 : far clear\n:org 0x200 jump far", 2, 17, "out of reach"},
+	{"unexpected end of file", "sprite v1", 1, 8, "unexpected end of file"},
+	{"recursive macro", ":macro forever { forever } forever", 1, 18, "nested too deeply"},
+	{"past the end of memory", ":org 0xFFFE clear\nreturn", 2, 1, "past the end of memory"},
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Assemble(test.source)
+			var octoErr *Error
+			if !errors.As(err, &octoErr) {
+				t.Fatalf("Assemble(%q) error = %v, expected an *Error", test.source, err)
+			}
+			if octoErr.Line != test.line || octoErr.Column != test.column || !strings.Contains(octoErr.Message, test.message) {
+				t.Errorf("Assemble(%q) error = %v, expected %d:%d: ...%s...", test.source, err, test.line, test.column, test.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package octo
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// token is a single whitespace separated word of Octo source, and where it was found
+type token struct {
+	text   string
+	line   int
+	column int
+	// depth is how many macro expansions deep the token was produced, 0 for tokens straight from the source
+	depth int
+}
+
+// Error is a problem with the Octo source, pointing at the line and column it was found at
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// errorAt creates an Error pointing at a token
+func errorAt(tok token, format string, args ...any) *Error {
+	return &Error{Line: tok.line, Column: tok.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// tokenize splits Octo source into tokens. Comments run from # to the end of the line.
+// Columns are counted in characters, starting from 1
+func tokenize(source string) []token {
+	var tokens []token
+	line, column := 1, 0
+	var current []rune
+	start := token{}
+	inComment := false
+
+	flush := func() {
+		if len(current) > 0 {
+			start.text = string(current)
+			tokens = append(tokens, start)
+			current = current[:0]
+		}
+	}
+
+	for _, r := range source {
+		column++
+		switch {
+		case r == '\n':
+			flush()
+			inComment = false
+			line++
+			column = 0
+		case inComment:
+		case r == '#':
+			flush()
+			inComment = true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if len(current) == 0 {
+				start = token{line: line, column: column}
+			}
+			current = append(current, r)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,120 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+const (
+	DEFAULT_REWIND_SECONDS = 10
+	// REWIND_FRAME_INTERVAL records every other frame, which still rewinds smoothly at 30 frames per second
+	REWIND_FRAME_INTERVAL = 2
+	// REWIND_MAX_BYTES caps the rewind history, which would otherwise grow to over 100MB for 30 seconds of XO-CHIP
+	REWIND_MAX_BYTES = 32 << 20
+)
+
+// rewindSecondsPresets are the lengths of rewind history offered in the UI
+var rewindSecondsPresets = []int{5, 10, 20, 30}
+
+var (
+	rewindMutex  sync.Mutex
+	rewindBuffer = newRewindBuffer(DEFAULT_REWIND_SECONDS)
+	isRewinding  bool
+	// rewindFrameCount counts frames towards the next recording, or the next step back while rewinding
+	rewindFrameCount int
+	// newestIsCurrent is set while the newest recorded state is the frame the machine is currently on
+	newestIsCurrent bool
+)
+
+// newRewindBuffer creates a buffer holding up to the given number of seconds of history
+func newRewindBuffer(seconds int) *chip8.RewindBuffer {
+	return chip8.NewRewindBuffer(seconds*DISPLAY_REFRESH_RATE/REWIND_FRAME_INTERVAL, REWIND_MAX_BYTES)
+}
+
+// setRewindSeconds resizes the rewind history, discarding anything already recorded
+func setRewindSeconds(seconds int) {
+	rewindMutex.Lock()
+	defer rewindMutex.Unlock()
+	rewindBuffer = newRewindBuffer(seconds)
+	newestIsCurrent = false
+}
+
+func setRewinding(rewinding bool) {
+	func() {
+		rewindMutex.Lock()
+		defer rewindMutex.Unlock()
+		isRewinding = rewinding
+	}()
+	wakeInterpreter()
+}
+
+func getRewinding() bool {
+	rewindMutex.Lock()
+	defer rewindMutex.Unlock()
+	return isRewinding
+}
+
+func clearRewindHistory() {
+	rewindMutex.Lock()
+	defer rewindMutex.Unlock()
+	rewindBuffer.Clear()
+	rewindFrameCount = 0
+	newestIsCurrent = false
+}
+
+// recordRewindFrame snapshots the machine into the rewind history every REWIND_FRAME_INTERVAL frames.
+// Should be called after each frame
+func recordRewindFrame() {
+	rewindMutex.Lock()
+	defer rewindMutex.Unlock()
+	newestIsCurrent = false
+	rewindFrameCount++
+	if rewindFrameCount < REWIND_FRAME_INTERVAL {
+		return
+	}
+	rewindFrameCount = 0
+	rewindBuffer.Push(machine.SaveState())
+	newestIsCurrent = true
+}
+
+// tryRewindFrame steps the machine back by one frame if rewinding is held, and reports whether it did.
+// Once the history runs out, the machine is held at the oldest recorded frame
+func tryRewindFrame() bool {
+	rewindMutex.Lock()
+	defer rewindMutex.Unlock()
+	if !isRewinding {
+		return false
+	}
+	// Step back at the pace the history was recorded, so that rewinding plays at normal speed
+	rewindFrameCount++
+	if rewindFrameCount < REWIND_FRAME_INTERVAL {
+		return true
+	}
+	rewindFrameCount = 0
+
+	if newestIsCurrent && rewindBuffer.Len() > 1 {
+		// Restoring the frame already on screen wouldn't visibly step back, so skip past it
+		rewindBuffer.Pop()
+	}
+	state, ok := rewindBuffer.Pop()
+	if !ok {
+		return true
+	}
+	newestIsCurrent = false
+	if rewindBuffer.Len() == 0 {
+		// Keep the oldest frame around, so that releasing the key resumes from it
+		rewindBuffer.Push(state)
+		newestIsCurrent = true
+	}
+	// Keep the keypad as the player is currently holding it, so keys don't get stuck down
+	state.Input = machine.Keys()
+	err := machine.LoadState(state)
+	if err != nil {
+		fmt.Println("failed to rewind:", err)
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+// Package roms is the library of ROMs built into the binary, shown in the Load ROM menu and runnable by name
+// from the command line
+package roms
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+// The ROM files are embedded from the files directory, see files/README.md for where each one comes from
+//
+//go:embed files
+var files embed.FS
+
+// DEFAULT_ROMS_DIR is where library ROMs that aren't built in are looked for, relative to the working directory,
+// unless CHIP8_ROMS_DIR is set. The files are laid out as they would be in the files directory
+const DEFAULT_ROMS_DIR = "../chip8-roms"
+
+// Dir returns the directory library ROMs that aren't built in are read from
+func Dir() string {
+	dir := os.Getenv("CHIP8_ROMS_DIR")
+	if dir == "" {
+		return DEFAULT_ROMS_DIR
+	}
+	return dir
+}
+
+// Rom describes a ROM in the library
+type Rom struct {
+	// Name is how the ROM is picked on the command line
+	Name     string
+	Title    string
+	Author   string
+	Platform string
+	// Mode is the hardware mode the ROM is best run in
+	Mode chip8.InterpreterMode
+	// File is the ROM's path within the files directory
+	File string
+}
+
+var library = []Rom{
+	{"testsuite1", "Test Suite 1 - CHIP-8 Logo", "Timendus", "CHIP-8", chip8.MODE_CHIP8, "tests/1-chip8-logo.ch8"},
+	{"testsuite2", "Test Suite 2 - IBM Logo", "Timendus", "CHIP-8", chip8.MODE_CHIP8, "tests/2-ibm-logo.ch8"},
+	{"testsuite3", "Test Suite 3 - Corax+ Opcodes", "Timendus", "CHIP-8", chip8.MODE_CHIP8, "tests/3-corax+.ch8"},
+	{"testsuite4", "Test Suite 4 - Flags", "Timendus", "CHIP-8", chip8.MODE_CHIP8, "tests/4-flags.ch8"},
+	{"testsuite5", "Test Suite 5 - Quirks", "Timendus", "CHIP-8, SUPER-CHIP, XO-CHIP", chip8.MODE_CHIP8, "tests/5-quirks.ch8"},
+	{"testsuite6", "Test Suite 6 - Keypad", "Timendus", "CHIP-8", chip8.MODE_CHIP8, "tests/6-keypad.ch8"},
+	{"octojam9title", "Octojam 9 Title", "", "XO-CHIP", chip8.MODE_XOCHIP, "octojam/octojam9title.ch8"},
+	{"selftest-opcodes", "Self Test - CHIP-8 Opcodes", "", "CHIP-8", chip8.MODE_CHIP8, "selftest/opcodes.ch8"},
+	{"selftest-quirks", "Self Test - Quirks", "", "CHIP-8, SUPER-CHIP, XO-CHIP", chip8.MODE_CHIP8, "selftest/quirks.ch8"},
+	{"selftest-xochip", "Self Test - XO-CHIP", "", "XO-CHIP", chip8.MODE_XOCHIP, "selftest/xochip.ch8"},
+}
+
+// Library returns every ROM in the library that has been built into the binary, or can be found in Dir
+func Library() []Rom {
+	var available []Rom
+	for _, rom := range library {
+		if _, err := fs.Stat(files, path.Join("files", rom.File)); err == nil {
+			available = append(available, rom)
+		} else if _, err := os.Stat(rom.path()); err == nil {
+			available = append(available, rom)
+		}
+	}
+	return available
+}
+
+// Find looks up a ROM in the library by name
+func Find(name string) (Rom, bool) {
+	for _, rom := range Library() {
+		if rom.Name == name {
+			return rom, true
+		}
+	}
+	return Rom{}, false
+}
+
+// Open opens a library ROM by name, ready to be loaded
+func Open(name string) (io.ReadCloser, error) {
+	rom, ok := Find(name)
+	if !ok {
+		return nil, fmt.Errorf("no ROM named %q in the library", name)
+	}
+	data, err := rom.Data()
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Data returns the contents of the ROM, from the binary if it's built in, otherwise from Dir
+func (rom Rom) Data() ([]byte, error) {
+	data, err := files.ReadFile(path.Join("files", rom.File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.ReadFile(rom.path())
+	}
+	return data, err
+}
+
+// path returns where the ROM would be found in Dir
+func (rom Rom) path() string {
+	return filepath.Join(Dir(), filepath.FromSlash(rom.File))
+}
+
+// Label describes the ROM for menus, as its title followed by its author when known
+func (rom Rom) Label() string {
+	if rom.Author == "" {
+		return rom.Title
+	}
+	return fmt.Sprintf("%s (%s)", rom.Title, rom.Author)
+}
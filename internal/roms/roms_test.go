@@ -0,0 +1,98 @@
+package roms
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+	"github.com/greenrock64/chip8-interpreter/internal/octo"
+)
+
+func TestLibrary(t *testing.T) {
+	library := Library()
+	if len(library) == 0 {
+		t.Fatal("no ROMs are built into the library")
+	}
+	for _, rom := range library {
+		t.Run(rom.Name, func(t *testing.T) {
+			file, err := Open(rom.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = chip8.New(rom.Mode).LoadRomBytes(data)
+			if err != nil {
+				t.Errorf("%s doesn't load in its own mode: %v", rom.File, err)
+			}
+		})
+	}
+}
+
+// TestRomsDir checks that library ROMs which aren't built in are read from CHIP8_ROMS_DIR
+func TestRomsDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CHIP8_ROMS_DIR", dir)
+	if _, ok := Find("testsuite1"); ok {
+		t.Fatal("testsuite1 is in the library before its file was added")
+	}
+
+	rom := []byte{0x12, 0x00}
+	err := os.MkdirAll(filepath.Join(dir, "tests"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "tests", "1-chip8-logo.ch8"), rom, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, ok := Find("testsuite1")
+	if !ok {
+		t.Fatalf("testsuite1 isn't in the library, despite being in %s", dir)
+	}
+	data, err := found.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, rom) {
+		t.Errorf("Data() = % X, expected % X", data, rom)
+	}
+}
+
+// TestSelfTestSources checks that each self test ROM was assembled from the current version of its source
+func TestSelfTestSources(t *testing.T) {
+	sources, err := fs.Glob(files, "files/selftest/*.8o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) == 0 {
+		t.Fatal("no self test sources found")
+	}
+	for _, source := range sources {
+		t.Run(source, func(t *testing.T) {
+			text, err := files.ReadFile(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := octo.Assemble(string(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			romPath := strings.TrimSuffix(source, ".8o") + ".ch8"
+			rom, err := files.ReadFile(romPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rom, expected) {
+				t.Errorf("%s doesn't match its source, reassemble it with: chip8-interpreter asm -o %s %s", romPath, romPath, source)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+const (
+	SAVE_STATE_SLOTS     = 8
+	SAVE_STATE_EXTENSION = ".ch8state"
+)
+
+var errNoRomLoaded = errors.New("no ROM is loaded")
+
+// stateFilePath returns where a save state slot is stored for the loaded ROM.
+// States live in the user's config directory, grouped by the hash of the ROM they belong to
+func stateFilePath(slot int) (string, error) {
+	romHash := machine.RomHash()
+	if romHash == "" {
+		return "", errNoRomLoaded
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "chip8-interpreter", "states", romHash, fmt.Sprintf("slot%d%s", slot, SAVE_STATE_EXTENSION)), nil
+}
+
+// saveStateSlot writes the running ROM's state into a numbered slot
+func saveStateSlot(slot int) error {
+	path, err := stateFilePath(slot)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	stateFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer stateFile.Close()
+	err = chip8.WriteState(stateFile, machine.SaveState())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved state to slot %d\n", slot)
+	return stateFile.Close()
+}
+
+// loadStateSlot restores the running ROM's state from a numbered slot
+func loadStateSlot(slot int) error {
+	path, err := stateFilePath(slot)
+	if err != nil {
+		return err
+	}
+	stateFile, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("slot %d is empty", slot)
+	} else if err != nil {
+		return err
+	}
+	defer stateFile.Close()
+
+	state, err := chip8.ReadState(stateFile)
+	if err != nil {
+		return err
+	}
+	err = machine.LoadState(state)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded state from slot %d\n", slot)
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package sound synthesises the buzzer, separately from any audio device, so that it can be tested and
+// recorded without sound hardware
+package sound
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+type Waveform int
+
+const (
+	WAVEFORM_SQUARE Waveform = iota
+	WAVEFORM_SINE
+	WAVEFORM_TRIANGLE
+)
+
+const (
+	SAMPLE_RATE = 44100
+
+	// XO-CHIP plays its 128-bit audio pattern at 4000 bits per second when the pitch register is at its default
+	XOCHIP_PATTERN_BASE_RATE = 4000
+)
+
+// State is the part of a Machine that decides what the buzzer plays
+type State struct {
+	Mode       chip8.InterpreterMode
+	SoundTimer uint8
+	Pattern    [chip8.AUDIO_PATTERN_SIZE]byte
+	Pitch      uint8
+}
+
+// StateOf reads the buzzer's state from a Machine
+func StateOf(m *chip8.Machine) State {
+	pattern, pitch := m.AudioPattern()
+	return State{
+		Mode:       m.Mode(),
+		SoundTimer: m.Registers().SoundTimer,
+		Pattern:    pattern,
+		Pitch:      pitch,
+	}
+}
+
+// usesPattern reports whether the state plays the XO-CHIP audio pattern, rather than the buzzer's own tone
+func (state State) usesPattern() bool {
+	return state.Mode == chip8.MODE_XOCHIP && state.Pattern != [chip8.AUDIO_PATTERN_SIZE]byte{}
+}
+
+// PatternBitRate returns how many bits of the XO-CHIP audio pattern are played each second at a pitch
+func PatternBitRate(pitch uint8) float64 {
+	return XOCHIP_PATTERN_BASE_RATE * math.Pow(2, (float64(pitch)-chip8.AUDIO_DEFAULT_PITCH)/48)
+}
+
+// Buzzer generates the buzzer's tone. It isn't safe for concurrent use
+type Buzzer struct {
+	Waveform  Waveform
+	Frequency float64
+	Volume    float64
+	// phase is the position through the current wave cycle, from 0 to 1, so that the wave carries on smoothly
+	// from one call to Samples to the next
+	phase float64
+}
+
+// NewBuzzer creates a Buzzer playing a quiet 440Hz square wave
+func NewBuzzer() *Buzzer {
+	return &Buzzer{
+		Waveform:  WAVEFORM_SQUARE,
+		Frequency: 440,
+		Volume:    0.25,
+	}
+}
+
+// Samples produces count samples at SAMPLE_RATE. They're silent unless the sound timer is running, and
+// XO-CHIP ROMs that have loaded an audio pattern play that pattern instead of the buzzer's tone
+func (b *Buzzer) Samples(count int, state State) []float32 {
+	samples := make([]float32, count)
+	if state.SoundTimer == 0 {
+		b.phase = 0
+		return samples
+	}
+
+	frequency := b.Frequency
+	usePattern := state.usesPattern()
+	if usePattern {
+		// The whole 128-bit pattern is one cycle of the wave
+		frequency = PatternBitRate(state.Pitch) / (chip8.AUDIO_PATTERN_SIZE * 8)
+	}
+
+	for i := range samples {
+		var sample float64
+		switch {
+		case usePattern:
+			bit := int(b.phase * chip8.AUDIO_PATTERN_SIZE * 8)
+			if state.Pattern[bit/8]&(0x80>>(bit%8)) > 0 {
+				sample = 1
+			} else {
+				sample = -1
+			}
+		case b.Waveform == WAVEFORM_SINE:
+			sample = math.Sin(2 * math.Pi * b.phase)
+		case b.Waveform == WAVEFORM_TRIANGLE:
+			sample = 1 - 4*math.Abs(b.phase-0.5)
+		default:
+			if b.phase < 0.5 {
+				sample = 1
+			} else {
+				sample = -1
+			}
+		}
+		samples[i] = float32(sample * b.Volume)
+
+		b.phase += frequency / SAMPLE_RATE
+		b.phase -= math.Floor(b.phase)
+	}
+	return samples
+}
+
+// WriteWAV writes samples as a mono 32-bit float WAV file
+func WriteWAV(w io.Writer, samples []float32) error {
+	const (
+		bytesPerSample = 4
+		formatFloat    = 3
+	)
+	dataSize := uint32(len(samples) * bytesPerSample)
+	header := []any{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(formatFloat), uint16(1),
+		uint32(SAMPLE_RATE), uint32(SAMPLE_RATE * bytesPerSample), uint16(bytesPerSample), uint16(bytesPerSample * 8),
+		[]byte("data"), dataSize,
+	}
+	for _, field := range header {
+		err := binary.Write(w, binary.LittleEndian, field)
+		if err != nil {
+			return err
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, samples)
+}
@@ -0,0 +1,119 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/greenrock64/chip8-interpreter/internal/chip8"
+)
+
+// crossings counts how many times the samples change sign
+func crossings(samples []float32) int {
+	count := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i] > 0) != (samples[i-1] > 0) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSquareWave(t *testing.T) {
+	buzzer := NewBuzzer()
+	buzzer.Frequency = 441
+	buzzer.Volume = 0.5
+	samples := buzzer.Samples(SAMPLE_RATE, State{Mode: chip8.MODE_CHIP8, SoundTimer: 1})
+
+	// 441Hz at 44100 samples per second is exactly 100 samples per cycle, high for the first half
+	for i, sample := range samples[:200] {
+		expected := float32(0.5)
+		if i%100 >= 50 {
+			expected = -0.5
+		}
+		if sample != expected {
+			t.Fatalf("sample %d = %v, expected %v", i, sample, expected)
+		}
+	}
+	// Each cycle changes sign twice
+	if count := crossings(samples); math.Abs(float64(count-2*441)) > 1 {
+		t.Errorf("%d sign changes in a second, expected %d for 441Hz", count, 2*441)
+	}
+}
+
+func TestSilentWithoutSoundTimer(t *testing.T) {
+	buzzer := NewBuzzer()
+	for i, sample := range buzzer.Samples(1000, State{Mode: chip8.MODE_CHIP8}) {
+		if sample != 0 {
+			t.Fatalf("sample %d = %v, expected silence while the sound timer is 0", i, sample)
+		}
+	}
+}
+
+func TestXOChipPattern(t *testing.T) {
+	// Alternating bits change sign on every bit, so the number of sign changes is the bit rate
+	var pattern [chip8.AUDIO_PATTERN_SIZE]byte
+	for i := range pattern {
+		pattern[i] = 0xAA
+	}
+	for _, pitch := range []uint8{chip8.AUDIO_DEFAULT_PITCH, 16, 112, 255} {
+		expectedRate := 4000 * math.Pow(2, (float64(pitch)-64)/48)
+		if rate := PatternBitRate(pitch); math.Abs(rate-expectedRate) > 1e-9 {
+			t.Errorf("PatternBitRate(%d) = %v, expected %v", pitch, rate, expectedRate)
+		}
+
+		if expectedRate > SAMPLE_RATE/2 {
+			// Too fast to count bits from the samples
+			continue
+		}
+		buzzer := NewBuzzer()
+		samples := buzzer.Samples(SAMPLE_RATE, State{Mode: chip8.MODE_XOCHIP, SoundTimer: 1, Pattern: pattern, Pitch: pitch})
+		if count := crossings(samples); math.Abs(float64(count)-expectedRate) > 2 {
+			t.Errorf("pitch %d: %d bits played in a second, expected %.1f", pitch, count, expectedRate)
+		}
+	}
+}
+
+func TestPatternOrder(t *testing.T) {
+	// The pattern is played from the most significant bit of the first byte, so one high bit then silence
+	// gives a single short pulse at the start of each cycle
+	var pattern [chip8.AUDIO_PATTERN_SIZE]byte
+	pattern[0] = 0x80
+	buzzer := NewBuzzer()
+	samples := buzzer.Samples(SAMPLE_RATE/4000*3, State{Mode: chip8.MODE_XOCHIP, SoundTimer: 1, Pattern: pattern, Pitch: chip8.AUDIO_DEFAULT_PITCH})
+	if samples[0] <= 0 || samples[len(samples)-1] >= 0 {
+		t.Errorf("samples start %v and end %v, expected a high first bit followed by low bits", samples[0], samples[len(samples)-1])
+	}
+}
+
+func TestPatternIgnoredOutsideXOChip(t *testing.T) {
+	var pattern [chip8.AUDIO_PATTERN_SIZE]byte
+	for i := range pattern {
+		pattern[i] = 0xAA
+	}
+	buzzer := NewBuzzer()
+	buzzer.Frequency = 441
+	samples := buzzer.Samples(SAMPLE_RATE, State{Mode: chip8.MODE_SUPERCHIP, SoundTimer: 1, Pattern: pattern})
+	if count := crossings(samples); math.Abs(float64(count-2*441)) > 1 {
+		t.Errorf("%d sign changes in a second, expected the buzzer's own 441Hz tone", count)
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteWAV(&buffer, []float32{0.5, -0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	if len(data) != 44+8 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Fatalf("WAV header = % X, expected a 44 byte RIFF header", data[:min(len(data), 44)])
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != SAMPLE_RATE {
+		t.Errorf("sample rate = %d, expected %d", rate, SAMPLE_RATE)
+	}
+	if sample := math.Float32frombits(binary.LittleEndian.Uint32(data[48:])); sample != -0.5 {
+		t.Errorf("second sample = %v, expected -0.5", sample)
+	}
+}
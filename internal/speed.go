@@ -0,0 +1,155 @@
+//go:build !nogui
+
+package internal
+
+import (
+	"slices"
+	"sync"
+)
+
+const (
+	TURBO_FRAME_MULTIPLIER = 5
+	SLOW_MOTION_DIVISOR    = 4
+)
+
+// speedPresets are the instructions-per-frame settings offered in the UI, slowest first
+var speedPresets = []int{7, 10, 15, 20, 30, 50, 100, 200, 500, 1000}
+
+var (
+	speedMutex           sync.Mutex
+	instructionsPerFrame = INSTRUCTIONS_PER_FRAME
+	isTurbo              bool
+	isSlowMotion         bool
+	isPaused             bool
+	pendingFrameAdvances int
+	slowMotionCounter    int
+
+	// onSpeedChanged is called after any speed setting changes, so that the UI can reflect it
+	onSpeedChanged = func() {}
+	// interpreterWakeChan wakes a paused interpreter loop to re-check whether it has work to do
+	interpreterWakeChan = make(chan bool, 1)
+)
+
+// wakeInterpreter nudges the interpreter loop, without blocking if it has already been nudged
+func wakeInterpreter() {
+	select {
+	case interpreterWakeChan <- true:
+	default:
+	}
+}
+
+// isIdle reports whether the interpreter is paused with no frames waiting to be advanced through
+func isIdle() bool {
+	speedMutex.Lock()
+	defer speedMutex.Unlock()
+	return isPaused && pendingFrameAdvances == 0
+}
+
+// framesToRun returns the number of frames the interpreter should run for the current vertical blank.
+// Whole frames are skipped or repeated, so the timers always tick in step with the instructions
+func framesToRun() int {
+	speedMutex.Lock()
+	defer speedMutex.Unlock()
+
+	if isPaused {
+		if pendingFrameAdvances > 0 {
+			pendingFrameAdvances--
+			return 1
+		}
+		return 0
+	}
+	if isTurbo {
+		return TURBO_FRAME_MULTIPLIER
+	}
+	if isSlowMotion {
+		slowMotionCounter++
+		if slowMotionCounter < SLOW_MOTION_DIVISOR {
+			return 0
+		}
+		slowMotionCounter = 0
+	}
+	return 1
+}
+
+func getInstructionsPerFrame() int {
+	speedMutex.Lock()
+	defer speedMutex.Unlock()
+	return instructionsPerFrame
+}
+
+func setInstructionsPerFrame(instructions int) {
+	func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		instructionsPerFrame = instructions
+	}()
+	onSpeedChanged()
+}
+
+// stepSpeedPreset moves to the next faster (or slower, if direction is negative) speed preset
+func stepSpeedPreset(direction int) {
+	current := getInstructionsPerFrame()
+	i, _ := slices.BinarySearch(speedPresets, current)
+	if direction > 0 && i < len(speedPresets) && speedPresets[i] == current {
+		i++
+	} else if direction < 0 {
+		i--
+	}
+	if i >= 0 && i < len(speedPresets) {
+		setInstructionsPerFrame(speedPresets[i])
+	}
+}
+
+func setTurbo(turbo bool) {
+	func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		isTurbo = turbo
+	}()
+	onSpeedChanged()
+}
+
+func toggleSlowMotion() {
+	func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		isSlowMotion = !isSlowMotion
+		slowMotionCounter = 0
+	}()
+	onSpeedChanged()
+}
+
+func togglePause() {
+	setPaused(!getPaused())
+}
+
+func setPaused(paused bool) {
+	func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		isPaused = paused
+		pendingFrameAdvances = 0
+	}()
+	interpreterLifecycle.SetPaused(paused)
+	wakeInterpreter()
+	onSpeedChanged()
+}
+
+func getPaused() bool {
+	speedMutex.Lock()
+	defer speedMutex.Unlock()
+	return isPaused
+}
+
+// advanceFrame pauses the interpreter if needed, and queues up a single frame to run
+func advanceFrame() {
+	func() {
+		speedMutex.Lock()
+		defer speedMutex.Unlock()
+		isPaused = true
+		pendingFrameAdvances++
+	}()
+	interpreterLifecycle.SetPaused(true)
+	wakeInterpreter()
+	onSpeedChanged()
+}
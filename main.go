@@ -1,9 +1,15 @@
 package main
 
 import (
-	chip8 "github.com/greenrock64/chip8-interpreter/internal"
+	"os"
+
+	"github.com/greenrock64/chip8-interpreter/internal/cli"
 )
 
 func main() {
-	chip8.RunApp()
+	cli.OpenUI = openUI
+	if exitCode, ok := cli.RunCommand(os.Args[1:]); ok {
+		os.Exit(exitCode)
+	}
+	runApp()
 }
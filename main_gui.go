@@ -0,0 +1,13 @@
+//go:build !nogui
+
+package main
+
+import (
+	chip8 "github.com/greenrock64/chip8-interpreter/internal"
+)
+
+var openUI = chip8.RunAppWithRom
+
+func runApp() {
+	chip8.RunApp()
+}
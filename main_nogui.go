@@ -0,0 +1,19 @@
+//go:build nogui
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/greenrock64/chip8-interpreter/internal/cli"
+)
+
+// Builds with the nogui tag leave out the UI, and with it the need for SDL and a display, leaving only the
+// command line
+var openUI func(startup cli.Startup) error
+
+func runApp() {
+	fmt.Fprintln(os.Stderr, "This build has no UI, run a command instead, such as run -headless, disasm, asm, or roms")
+	os.Exit(2)
+}